@@ -0,0 +1,125 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// j2000 - юлианская дата эпохи J2000.0
+	j2000 = 2451545.0
+	// unixEpochJulian - юлианская дата начала Unix-времени
+	unixEpochJulian = 2440587.5
+	// earthObliquity - наклон земной оси, градусы
+	earthObliquity = 23.4397
+	// sunAltitude - высота центра Солнца при восходе и закате с учетом рефракции, градусы
+	sunAltitude = -0.833
+
+	// synodicMonth - продолжительность синодического месяца, дни
+	synodicMonth = 29.530588853
+	// knownNewMoon - юлианская дата новолуния 6 января 2000 года
+	knownNewMoon = 2451550.26
+)
+
+// SunInfo содержит время восхода и заката Солнца для дня
+type SunInfo struct {
+	Sunrise   time.Time
+	Sunset    time.Time
+	DayLength time.Duration
+	// PolarDay - Солнце не заходит весь день
+	PolarDay bool
+	// PolarNight - Солнце не восходит весь день
+	PolarNight bool
+}
+
+// MoonInfo содержит положение Луны в лунном цикле
+type MoonInfo struct {
+	// Age - возраст Луны в днях с последнего новолуния
+	Age float64
+	// Phase - доля пройденного лунного цикла от 0 (новолуние) до 1
+	Phase float64
+	// Illumination - освещенная доля диска от 0 до 1
+	Illumination float64
+}
+
+// Sun вычисляет восход и закат для календарного дня date в точке (lat, lon).
+// Время возвращается в часовом поясе date.
+func Sun(date time.Time, lat, lon float64) SunInfo {
+	midnight := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	n := math.Ceil(toJulian(midnight) - j2000 + 0.0008)
+
+	meanSolarTime := n - lon/360
+	anomaly := normalizeDegrees(357.5291 + 0.98560028*meanSolarTime)
+	center := 1.9148*sinDeg(anomaly) + 0.0200*sinDeg(2*anomaly) + 0.0003*sinDeg(3*anomaly)
+	eclipticLongitude := normalizeDegrees(anomaly + center + 180 + 102.9372)
+	transit := j2000 + meanSolarTime + 0.0053*sinDeg(anomaly) - 0.0069*sinDeg(2*eclipticLongitude)
+
+	sinDeclination := sinDeg(eclipticLongitude) * sinDeg(earthObliquity)
+	cosDeclination := math.Cos(math.Asin(sinDeclination))
+	cosHourAngle := (sinDeg(sunAltitude) - sinDeg(lat)*sinDeclination) / (cosDeg(lat) * cosDeclination)
+
+	switch {
+	case cosHourAngle < -1:
+		return SunInfo{PolarDay: true, DayLength: 24 * time.Hour}
+	case cosHourAngle > 1:
+		return SunInfo{PolarNight: true}
+	}
+
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+
+	sunrise := fromJulian(transit - hourAngle/360).In(date.Location())
+	sunset := fromJulian(transit + hourAngle/360).In(date.Location())
+
+	return SunInfo{
+		Sunrise:   sunrise,
+		Sunset:    sunset,
+		DayLength: sunset.Sub(sunrise),
+	}
+}
+
+// Moon вычисляет фазу Луны на момент t
+func Moon(t time.Time) MoonInfo {
+	age := math.Mod(toJulian(t)-knownNewMoon, synodicMonth)
+	if age < 0 {
+		age += synodicMonth
+	}
+
+	phase := age / synodicMonth
+
+	return MoonInfo{
+		Age:          age,
+		Phase:        phase,
+		Illumination: (1 - math.Cos(2*math.Pi*phase)) / 2,
+	}
+}
+
+// PhaseIndex возвращает номер одной из восьми фаз Луны:
+// 0 - новолуние, 2 - первая четверть, 4 - полнолуние, 6 - последняя четверть
+func (m MoonInfo) PhaseIndex() int {
+	return int(math.Floor(m.Phase*8+0.5)) % 8
+}
+
+func toJulian(t time.Time) float64 {
+	return float64(t.UnixNano())/float64(24*time.Hour) + unixEpochJulian
+}
+
+func fromJulian(j float64) time.Time {
+	return time.Unix(0, int64((j-unixEpochJulian)*float64(24*time.Hour))).UTC()
+}
+
+func normalizeDegrees(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+
+	return deg
+}
+
+func sinDeg(deg float64) float64 {
+	return math.Sin(deg * math.Pi / 180)
+}
+
+func cosDeg(deg float64) float64 {
+	return math.Cos(deg * math.Pi / 180)
+}
@@ -0,0 +1,165 @@
+// Package broadcast отправляет сообщения в Telegram с учетом ограничений Bot API: лимита сообщений
+// в секунду, лимита на один чат и ответов 429 (FloodError). Лимиты действуют в пределах процесса;
+// общий для всех реплик лимит рассылки соблюдает очередь задач.
+// Сообщение, на которое Telegram ответил FloodError, не повторяется: ошибка возвращается вызывающему,
+// а отправка в этом процессе приостанавливается на retry_after.
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// chatRate, chatBurst - лимит сообщений в один чат: Telegram не рекомендует больше одного в секунду,
+	// но допускает короткие серии
+	chatRate  = 1.0
+	chatBurst = 3
+	// idleChatTTL - через сколько удаляется лимит чата, в который давно не отправляли
+	idleChatTTL = time.Minute
+)
+
+// Sender ограничивает скорость отправки сообщений
+type Sender struct {
+	global *bucket
+
+	mu    sync.Mutex
+	chats map[int64]*bucket
+	// swept - когда из chats последний раз удалялись лимиты неактивных чатов
+	swept time.Time
+	// pausedUntil - до какого времени отправка приостановлена после FloodError
+	pausedUntil time.Time
+}
+
+// New создает отправителя с общим лимитом rate сообщений в секунду
+func New(rate float64) *Sender {
+	return &Sender{
+		global: newBucket(rate, max(int(rate), 1)),
+		chats:  make(map[int64]*bucket),
+	}
+}
+
+// Send выполняет send - отправку одного сообщения в чат chatID - с учетом лимитов.
+// При FloodError вся отправка приостанавливается на retry_after, а ошибка возвращается:
+// повторить сообщение должен вызывающий, например через очередь задач.
+func (s *Sender) Send(ctx context.Context, chatID int64, send func() error) error {
+	if err := s.wait(ctx, chatID); err != nil {
+		return err
+	}
+
+	err := send()
+
+	var flood tele.FloodError
+	if errors.As(err, &flood) {
+		s.pause(time.Duration(flood.RetryAfter) * time.Second)
+	}
+
+	return err
+}
+
+// wait ждет, пока можно будет отправить сообщение в чат chatID
+func (s *Sender) wait(ctx context.Context, chatID int64) error {
+	for {
+		now := time.Now()
+
+		s.mu.Lock()
+		delay := s.pausedUntil.Sub(now)
+		if delay <= 0 {
+			chat := s.chat(chatID, now)
+			delay = max(chat.delay(now), s.global.delay(now))
+			if delay <= 0 {
+				chat.take(now)
+				s.global.take(now)
+			}
+		}
+		s.mu.Unlock()
+
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pause приостанавливает отправку на d
+func (s *Sender) pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+// chat возвращает лимит чата chatID. Не чаще раза в idleChatTTL удаляет лимиты давно неактивных
+// чатов, поэтому рассылка по новым чатам не перебирает все лимиты на каждое сообщение. Вызывается под s.mu.
+func (s *Sender) chat(chatID int64, now time.Time) *bucket {
+	chat, ok := s.chats[chatID]
+	if ok {
+		return chat
+	}
+
+	if now.Sub(s.swept) > idleChatTTL {
+		for id, b := range s.chats {
+			if now.Sub(b.updated) > idleChatTTL {
+				delete(s.chats, id)
+			}
+		}
+		s.swept = now
+	}
+
+	chat = newBucket(chatRate, chatBurst)
+	s.chats[chatID] = chat
+
+	return chat
+}
+
+// bucket - ограничитель скорости "token bucket": rate токенов в секунду, не больше burst
+type bucket struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	return &bucket{
+		rate:    rate,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		updated: time.Now(),
+	}
+}
+
+// refill начисляет токены за время с последнего обновления
+func (b *bucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+}
+
+// delay возвращает, сколько ждать до появления токена
+func (b *bucket) delay(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// take забирает токен
+func (b *bucket) take(now time.Time) {
+	b.refill(now)
+	b.tokens--
+}
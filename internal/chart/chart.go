@@ -0,0 +1,218 @@
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	width  = 800
+	height = 400
+
+	marginLeft   = 60
+	marginRight  = 50
+	marginTop    = 30
+	marginBottom = 40
+
+	// lineThickness - толщина линии температуры в пикселях
+	lineThickness = 3
+	// barFill - доля интервала между точками, занимаемая столбцом осадков
+	barFill = 0.6
+)
+
+var (
+	backgroundColor    = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	gridColor          = color.RGBA{R: 225, G: 225, B: 225, A: 255}
+	zeroLineColor      = color.RGBA{R: 150, G: 150, B: 150, A: 255}
+	daySeparatorColor  = color.RGBA{R: 190, G: 190, B: 190, A: 255}
+	temperatureColor   = color.RGBA{R: 230, G: 90, B: 40, A: 255}
+	precipitationColor = color.RGBA{R: 120, G: 170, B: 230, A: 255}
+	labelColor         = color.RGBA{R: 80, G: 80, B: 80, A: 255}
+)
+
+// ErrNotEnoughPoints возвращается, если точек недостаточно для построения графика
+var ErrNotEnoughPoints = errors.New("not enough points to render chart")
+
+// Point содержит значения прогноза на момент времени
+type Point struct {
+	Time        time.Time
+	Temperature float64
+	// Precipitation - количество осадков за интервал до этой точки от предыдущей, мм
+	Precipitation float64
+}
+
+// Render рисует график температуры (линия) и осадков (столбцы) и записывает его в w в формате PNG.
+// Подписи времени выводятся в часовом поясе точек.
+func Render(w io.Writer, points []Point) error {
+	if len(points) < 2 {
+		return ErrNotEnoughPoints
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, 0, 0, width, height, backgroundColor)
+
+	plot := image.Rect(marginLeft, marginTop, width-marginRight, height-marginBottom)
+
+	minTemperature, maxTemperature, temperatureStep := temperatureScale(points)
+	maxPrecipitation := precipitationScale(points)
+
+	step := float64(plot.Dx()) / float64(len(points)-1)
+	xAt := func(i int) int {
+		return plot.Min.X + int(math.Round(float64(i)*step))
+	}
+	yAtTemperature := func(t float64) int {
+		ratio := (t - minTemperature) / (maxTemperature - minTemperature)
+		return plot.Max.Y - int(math.Round(ratio*float64(plot.Dy())))
+	}
+	yAtPrecipitation := func(p float64) int {
+		return plot.Max.Y - int(math.Round(p/maxPrecipitation*float64(plot.Dy())))
+	}
+
+	// Столбцы осадков
+	barWidth := int(step * barFill)
+	for i, point := range points {
+		if point.Precipitation <= 0 {
+			continue
+		}
+
+		x0 := max(xAt(i)-barWidth/2, plot.Min.X)
+		x1 := min(xAt(i)+barWidth/2, plot.Max.X)
+		fillRect(img, x0, yAtPrecipitation(point.Precipitation), x1, plot.Max.Y, precipitationColor)
+	}
+
+	// Сетка и подписи температуры
+	for t := minTemperature; t <= maxTemperature+temperatureStep/2; t += temperatureStep {
+		y := yAtTemperature(t)
+
+		lineColor := gridColor
+		if math.Abs(t) < temperatureStep/2 {
+			lineColor = zeroLineColor
+		}
+		fillRect(img, plot.Min.X, y, plot.Max.X, y+1, lineColor)
+
+		label := formatTemperature(t)
+		drawText(img, plot.Min.X-textWidth(label)-8, y-textHeight()/2, label, labelColor)
+	}
+
+	// Подписи осадков на правой оси
+	drawText(img, plot.Max.X+8, plot.Max.Y-textHeight()/2, "0", precipitationColor)
+	maxLabel := formatPrecipitation(maxPrecipitation)
+	drawText(img, plot.Max.X+8, plot.Min.Y-textHeight()/2, maxLabel, precipitationColor)
+
+	// Разделители дней и подписи времени
+	for i, point := range points {
+		x := xAt(i)
+
+		if i > 0 && point.Time.Day() != points[i-1].Time.Day() {
+			for y := plot.Min.Y; y < plot.Max.Y; y += 6 {
+				fillRect(img, x, y, x+1, y+3, daySeparatorColor)
+			}
+
+			label := point.Time.Format("02.01")
+			drawText(img, x-textWidth(label)/2, plot.Min.Y-textHeight()-8, label, labelColor)
+		}
+
+		if i%2 == 0 {
+			label := point.Time.Format("15")
+			drawText(img, x-textWidth(label)/2, plot.Max.Y+10, label, labelColor)
+		}
+	}
+
+	// Линия температуры
+	for i := 1; i < len(points); i++ {
+		drawLine(
+			img,
+			xAt(i-1), yAtTemperature(points[i-1].Temperature),
+			xAt(i), yAtTemperature(points[i].Temperature),
+			temperatureColor,
+		)
+	}
+	for i, point := range points {
+		x, y := xAt(i), yAtTemperature(point.Temperature)
+		fillRect(img, x-3, y-3, x+4, y+4, temperatureColor)
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode chart: %w", err)
+	}
+
+	return nil
+}
+
+// temperatureScale подбирает границы и шаг сетки оси температуры
+func temperatureScale(points []Point) (float64, float64, float64) {
+	lo, hi := points[0].Temperature, points[0].Temperature
+	for _, point := range points {
+		lo = math.Min(lo, point.Temperature)
+		hi = math.Max(hi, point.Temperature)
+	}
+
+	step := 1.0
+	for _, candidate := range []float64{1, 2, 5, 10, 20} {
+		step = candidate
+		if (hi-lo)/candidate <= 8 {
+			break
+		}
+	}
+
+	lo = math.Floor(lo/step)*step - step
+	hi = math.Ceil(hi/step)*step + step
+
+	return lo, hi, step
+}
+
+// precipitationScale подбирает верхнюю границу оси осадков, не меньше 1 мм
+func precipitationScale(points []Point) float64 {
+	hi := 1.0
+	for _, point := range points {
+		hi = math.Max(hi, point.Precipitation)
+	}
+
+	return math.Ceil(hi)
+}
+
+// formatTemperature форматирует значение температуры для подписи оси
+func formatTemperature(t float64) string {
+	if math.Round(t) == 0 {
+		return "0°"
+	}
+
+	return fmt.Sprintf("%+.0f°", t)
+}
+
+// formatPrecipitation форматирует значение осадков для подписи оси
+func formatPrecipitation(p float64) string {
+	return fmt.Sprintf("%.0f", p)
+}
+
+// fillRect заливает прямоугольник [x0, x1) x [y0, y1) цветом c
+func fillRect(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	draw.Draw(img, image.Rect(x0, y0, x1, y1), &image.Uniform{C: c}, image.Point{}, draw.Over)
+}
+
+// drawLine рисует отрезок толщиной lineThickness
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	half := lineThickness / 2
+
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		fillRect(img, x-half, y-half, x-half+lineThickness, y-half+lineThickness, c)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
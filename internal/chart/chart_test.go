@@ -0,0 +1,137 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// update перезаписывает эталонные изображения: go test ./internal/chart -update
+var update = flag.Bool("update", false, "update golden chart images")
+
+// forecastStep - интервал между точками прогноза OpenWeather
+const forecastStep = 3 * time.Hour
+
+// fixture строит точки прогноза с шагом forecastStep, начиная с start
+func fixture(start time.Time, temperatures, precipitation []float64) []Point {
+	points := make([]Point, len(temperatures))
+	for i := range temperatures {
+		points[i] = Point{
+			Time:          start.Add(time.Duration(i) * forecastStep),
+			Temperature:   temperatures[i],
+			Precipitation: precipitation[i],
+		}
+	}
+
+	return points
+}
+
+func TestRenderGolden(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name   string
+		points []Point
+	}{
+		{
+			name: "summer_rain",
+			points: fixture(
+				time.Date(2026, time.July, 14, 9, 0, 0, 0, moscow),
+				[]float64{18, 21, 24, 25, 22, 19, 17, 16, 18, 22, 26, 27, 23, 20, 18, 17},
+				[]float64{0, 0, 0.4, 2.5, 1.2, 0, 0, 0, 0, 0, 0, 0.3, 0, 0, 0, 0},
+			),
+		},
+		{
+			name: "winter_thaw",
+			points: fixture(
+				time.Date(2026, time.January, 20, 0, 0, 0, 0, moscow),
+				[]float64{-12, -14, -9, -4, -2, 1, 2, -1, -3, -6, -2, 0, 3, 1, -2, -5},
+				[]float64{0, 0, 0, 0.8, 3.6, 4.2, 1.1, 0, 0, 0, 0, 0.2, 0.5, 0, 0, 0},
+			),
+		},
+		{
+			name: "flat_dry",
+			points: fixture(
+				time.Date(2026, time.October, 19, 6, 0, 0, 0, time.UTC),
+				[]float64{10, 10, 10, 10, 10},
+				[]float64{0, 0, 0, 0, 0},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Render(&buf, tt.points); err != nil {
+					t.Fatalf("Render() error = %v", err)
+				}
+
+				golden := filepath.Join("testdata", tt.name+".png")
+
+				if *update {
+					if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+						t.Fatalf("failed to update %s: %v", golden, err)
+					}
+				}
+
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("failed to read %s (run with -update to create it): %v", golden, err)
+				}
+
+				// Сравниваются пиксели, а не байты PNG: сжатие может отличаться между версиями Go
+				if diff := diffImages(t, buf.Bytes(), want); diff != "" {
+					t.Errorf("chart differs from %s: %s (run with -update if the change is intended)", golden, diff)
+				}
+			},
+		)
+	}
+}
+
+func TestRenderNotEnoughPoints(t *testing.T) {
+	points := fixture(time.Date(2026, time.October, 19, 6, 0, 0, 0, time.UTC), []float64{10}, []float64{0})
+
+	if err := Render(&bytes.Buffer{}, points); !errors.Is(err, ErrNotEnoughPoints) {
+		t.Errorf("Render() error = %v, want %v", err, ErrNotEnoughPoints)
+	}
+}
+
+// diffImages сравнивает два PNG попиксельно и описывает первое отличие; пустая строка - изображения совпадают
+func diffImages(t *testing.T, got, want []byte) string {
+	t.Helper()
+
+	gotImg, err := png.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("failed to decode rendered chart: %v", err)
+	}
+
+	wantImg, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("failed to decode golden chart: %v", err)
+	}
+
+	if gotImg.Bounds() != wantImg.Bounds() {
+		return "size " + gotImg.Bounds().String() + ", want " + wantImg.Bounds().String()
+	}
+
+	bounds := gotImg.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := gotImg.At(x, y).RGBA()
+			r2, g2, b2, a2 := wantImg.At(x, y).RGBA()
+
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return "first difference at " + image.Pt(x, y).String()
+			}
+		}
+	}
+
+	return ""
+}
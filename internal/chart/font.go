@@ -0,0 +1,73 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+	glyphScale  = 2
+	glyphGap    = 1
+)
+
+// glyphs - растровый шрифт 3x5 для подписей осей: цифры и знаки
+var glyphs = map[rune][glyphHeight]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'°': {"##.", "##.", "...", "...", "..."},
+	' ': {"...", "...", "...", "...", "..."},
+}
+
+// textWidth возвращает ширину строки в пикселях
+func textWidth(text string) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+
+	return n*(glyphWidth+glyphGap)*glyphScale - glyphGap*glyphScale
+}
+
+// textHeight возвращает высоту строки в пикселях
+func textHeight() int {
+	return glyphHeight * glyphScale
+}
+
+// drawText рисует строку, начиная с верхнего левого угла (x, y).
+// Символы, которых нет в шрифте, пропускаются.
+func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
+	for _, r := range text {
+		glyph, ok := glyphs[r]
+		if ok {
+			for row, line := range glyph {
+				for col, pixel := range line {
+					if pixel != '#' {
+						continue
+					}
+					fillRect(
+						img,
+						x+col*glyphScale, y+row*glyphScale,
+						x+(col+1)*glyphScale, y+(row+1)*glyphScale,
+						c,
+					)
+				}
+			}
+		}
+
+		x += (glyphWidth + glyphGap) * glyphScale
+	}
+}
@@ -2,14 +2,23 @@ package dto
 
 // OpenWeatherResponse структура ответа от OpenWeather API
 type OpenWeatherResponse struct {
+	Coord struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"coord"`
 	Main struct {
 		Temp      float64 `json:"temp"`
 		FeelsLike float64 `json:"feels_like"`
+		Pressure  float64 `json:"pressure"`
 		Humidity  int     `json:"humidity"`
 	} `json:"main"`
 	Weather []struct {
+		// ID - код погодных условий
+		ID          int    `json:"id"`
 		Description string `json:"description"`
 		Main        string `json:"main"`
+		// Icon - код значка, суффикс "d" - день, "n" - ночь
+		Icon string `json:"icon"`
 	} `json:"weather"`
 	Wind struct {
 		Speed float64 `json:"speed"`
@@ -20,4 +29,58 @@ type OpenWeatherResponse struct {
 	Snow struct {
 		OneH float64 `json:"1h"`
 	} `json:"snow,omitempty"`
+	Sys struct {
+		Country string `json:"country"`
+	} `json:"sys"`
+	// Timezone - смещение от UTC в секундах
+	Timezone int    `json:"timezone"`
+	Name     string `json:"name"`
+}
+
+// AirPollutionResponse структура ответа от OpenWeather Air Pollution API
+type AirPollutionResponse struct {
+	List []struct {
+		Main struct {
+			// AQI - индекс качества воздуха от 1 (хорошее) до 5 (очень плохое)
+			AQI int `json:"aqi"`
+		} `json:"main"`
+		Components struct {
+			PM25 float64 `json:"pm2_5"`
+			PM10 float64 `json:"pm10"`
+		} `json:"components"`
+	} `json:"list"`
+}
+
+// ForecastResponse структура ответа от OpenWeather API с прогнозом на 5 дней с шагом 3 часа
+type ForecastResponse struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			Temp      float64 `json:"temp"`
+			FeelsLike float64 `json:"feels_like"`
+			TempMin   float64 `json:"temp_min"`
+			TempMax   float64 `json:"temp_max"`
+			Humidity  int     `json:"humidity"`
+		} `json:"main"`
+		Weather []struct {
+			ID          int    `json:"id"`
+			Description string `json:"description"`
+			Main        string `json:"main"`
+			Icon        string `json:"icon"`
+		} `json:"weather"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Pop  float64 `json:"pop"`
+		Rain struct {
+			ThreeH float64 `json:"3h"`
+		} `json:"rain,omitempty"`
+		Snow struct {
+			ThreeH float64 `json:"3h"`
+		} `json:"snow,omitempty"`
+	} `json:"list"`
+	City struct {
+		// Timezone - смещение от UTC в секундах
+		Timezone int `json:"timezone"`
+	} `json:"city"`
 }
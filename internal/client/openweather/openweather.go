@@ -3,12 +3,17 @@ package openweather
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/qrave1/DeepCakeBot/internal/client/openweather/dto"
+	"github.com/qrave1/DeepCakeBot/internal/condition"
 )
 
 // OpenWeatherClient клиент для работы с OpenWeather API
@@ -29,65 +34,255 @@ func NewOpenWeatherClient(apiKey string) *OpenWeatherClient {
 	}
 }
 
-// WeatherData содержит информацию о погоде
+// defaultLang - язык описания погоды, если он не указан
+const defaultLang = "ru"
+
+// ErrLocationNotFound возвращается, если API не нашло запрошенное место
+var ErrLocationNotFound = errors.New("location not found")
+
+// Location задает место для запроса погоды: по координатам, если они заданы,
+// иначе по названию города
+type Location struct {
+	Name        string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
+}
+
+// HasCoordinates сообщает, заданы ли координаты места
+func (l Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// query формирует параметры запроса для места
+func (l Location) query() url.Values {
+	query := url.Values{}
+
+	switch {
+	case l.HasCoordinates():
+		query.Set("lat", strconv.FormatFloat(l.Latitude, 'f', -1, 64))
+		query.Set("lon", strconv.FormatFloat(l.Longitude, 'f', -1, 64))
+	case l.CountryCode != "":
+		query.Set("q", fmt.Sprintf("%s,%s", l.Name, l.CountryCode))
+	default:
+		query.Set("q", l.Name)
+	}
+
+	return query
+}
+
+// WeatherData содержит информацию о погоде.
+// Значения хранятся в метрических единицах: температура в °C, ветер в м/с, давление в гПа.
 type WeatherData struct {
+	// City - название города по данным провайдера
+	City        string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
 	Temperature float64
 	FeelsLike   float64
+	// Condition - погодные условия по коду провайдера
+	Condition condition.Condition
+	// Night - сейчас ночь (по значку провайдера)
+	Night bool
+	// Description - описание погоды от провайдера на языке запроса
 	Description string
 	Humidity    int
 	WindSpeed   float64
-	Rain        bool
-	Snow        bool
+	Pressure    float64
+	// Rain, Snow - количество дождя и снега за последний час, мм
+	Rain float64
+	Snow float64
+	// Location - часовой пояс города
+	Location *time.Location
 }
 
-// GetCurrentWeather получает текущую погоду для указанного города
-func (c *OpenWeatherClient) GetCurrentWeather(ctx context.Context, city, countryCode string) (*WeatherData, error) {
-	url := fmt.Sprintf(
-		"%s/weather?q=%s,%s&appid=%s&units=metric&lang=ru",
-		c.baseURL,
-		city,
-		countryCode,
-		c.apiKey,
-	)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+// IsRaining сообщает, идет ли дождь: по коду условий или по количеству осадков,
+// так как количество осадков приходит не всегда
+func (w *WeatherData) IsRaining() bool {
+	return w.Condition.IsRain() || w.Rain > 0
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch weather: %w", err)
-	}
-	defer resp.Body.Close()
+// IsSnowing сообщает, идет ли снег: по коду условий или по количеству осадков
+func (w *WeatherData) IsSnowing() bool {
+	return w.Condition.IsSnow() || w.Snow > 0
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("weather API returned status %d: %s", resp.StatusCode, string(body))
-	}
+// ForecastEntry содержит прогноз погоды на трехчасовой интервал в метрических единицах
+type ForecastEntry struct {
+	// Time - время прогноза в местном времени города; Rain и Snow относятся к трем часам до него
+	Time        time.Time
+	Temperature float64
+	FeelsLike   float64
+	TempMin     float64
+	TempMax     float64
+	Condition   condition.Condition
+	Night       bool
+	Description string
+	Humidity    int
+	WindSpeed   float64
+	// PrecipitationProbability - вероятность осадков от 0 до 1
+	PrecipitationProbability float64
+	// Rain - количество дождя за три часа до Time, мм
+	Rain float64
+	// Snow - количество снега за три часа до Time, мм
+	Snow float64
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+// AirQuality содержит данные о качестве воздуха
+type AirQuality struct {
+	// Index - индекс качества воздуха от 1 (хорошее) до 5 (очень плохое)
+	Index int
+	// PM25, PM10 - концентрация мелких и крупных взвешенных частиц, мкг/м³
+	PM25 float64
+	PM10 float64
+}
 
+// GetCurrentWeather получает текущую погоду для указанного места.
+// lang - язык описания погоды (код ISO 639-1).
+func (c *OpenWeatherClient) GetCurrentWeather(ctx context.Context, location Location, lang string) (
+	*WeatherData,
+	error,
+) {
 	var openWeatherResponse dto.OpenWeatherResponse
-	if err := json.Unmarshal(body, &openWeatherResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse weather response: %w", err)
+	if err := c.get(ctx, "/weather", location.query(), lang, &openWeatherResponse); err != nil {
+		return nil, err
 	}
 
 	weather := &WeatherData{
+		City:        openWeatherResponse.Name,
+		CountryCode: openWeatherResponse.Sys.Country,
+		Latitude:    openWeatherResponse.Coord.Lat,
+		Longitude:   openWeatherResponse.Coord.Lon,
 		Temperature: openWeatherResponse.Main.Temp,
 		FeelsLike:   openWeatherResponse.Main.FeelsLike,
 		Humidity:    openWeatherResponse.Main.Humidity,
 		WindSpeed:   openWeatherResponse.Wind.Speed,
-		Rain:        openWeatherResponse.Rain.OneH > 0,
-		Snow:        openWeatherResponse.Snow.OneH > 0,
+		Pressure:    openWeatherResponse.Main.Pressure,
+		Location:    time.FixedZone("", openWeatherResponse.Timezone),
 	}
 
 	if len(openWeatherResponse.Weather) > 0 {
-		weather.Description = openWeatherResponse.Weather[0].Description
+		item := openWeatherResponse.Weather[0]
+
+		weather.Condition = condition.FromOpenWeather(item.ID)
+		weather.Night = isNightIcon(item.Icon)
+		weather.Description = item.Description
 	}
 
+	weather.Rain = openWeatherResponse.Rain.OneH
+	weather.Snow = openWeatherResponse.Snow.OneH
+
 	return weather, nil
 }
+
+// GetForecast получает прогноз погоды на 5 дней с шагом 3 часа для указанного места.
+// lang - язык описания погоды (код ISO 639-1).
+func (c *OpenWeatherClient) GetForecast(ctx context.Context, location Location, lang string) (
+	[]ForecastEntry,
+	error,
+) {
+	var forecastResponse dto.ForecastResponse
+	if err := c.get(ctx, "/forecast", location.query(), lang, &forecastResponse); err != nil {
+		return nil, err
+	}
+
+	timezone := time.FixedZone("", forecastResponse.City.Timezone)
+
+	entries := make([]ForecastEntry, 0, len(forecastResponse.List))
+	for _, item := range forecastResponse.List {
+		entry := ForecastEntry{
+			Time:                     time.Unix(item.Dt, 0).In(timezone),
+			Temperature:              item.Main.Temp,
+			FeelsLike:                item.Main.FeelsLike,
+			TempMin:                  item.Main.TempMin,
+			TempMax:                  item.Main.TempMax,
+			Humidity:                 item.Main.Humidity,
+			WindSpeed:                item.Wind.Speed,
+			PrecipitationProbability: item.Pop,
+			Rain:                     item.Rain.ThreeH,
+			Snow:                     item.Snow.ThreeH,
+		}
+
+		if len(item.Weather) > 0 {
+			entry.Condition = condition.FromOpenWeather(item.Weather[0].ID)
+			entry.Night = isNightIcon(item.Weather[0].Icon)
+			entry.Description = item.Weather[0].Description
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetAirQuality получает текущее качество воздуха в точке с координатами latitude, longitude
+func (c *OpenWeatherClient) GetAirQuality(ctx context.Context, latitude, longitude float64) (*AirQuality, error) {
+	location := Location{Latitude: latitude, Longitude: longitude}
+
+	var airPollutionResponse dto.AirPollutionResponse
+	if err := c.get(ctx, "/air_pollution", location.query(), "", &airPollutionResponse); err != nil {
+		return nil, err
+	}
+
+	if len(airPollutionResponse.List) == 0 {
+		return nil, errors.New("air pollution API returned no data")
+	}
+
+	item := airPollutionResponse.List[0]
+
+	return &AirQuality{
+		Index: item.Main.AQI,
+		PM25:  item.Components.PM25,
+		PM10:  item.Components.PM10,
+	}, nil
+}
+
+// isNightIcon сообщает, обозначает ли код значка OpenWeather ночь ("01n")
+func isNightIcon(icon string) bool {
+	return strings.HasSuffix(icon, "n")
+}
+
+// get выполняет GET-запрос к API и декодирует JSON-ответ в out
+func (c *OpenWeatherClient) get(ctx context.Context, path string, query url.Values, lang string, out interface{}) error {
+	if lang == "" {
+		lang = defaultLang
+	}
+
+	query.Set("appid", c.apiKey)
+	// Данные всегда запрашиваются в метрических единицах,
+	// в единицы пользователя они переводятся только при форматировании
+	query.Set("units", "metric")
+	query.Set("lang", lang)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch weather: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrLocationNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("weather API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse weather response: %w", err)
+	}
+
+	return nil
+}
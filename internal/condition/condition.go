@@ -0,0 +1,94 @@
+package condition
+
+// Condition - погодные условия, не зависящие от провайдера и языка его описаний
+type Condition string
+
+const (
+	Unknown      Condition = ""
+	Clear        Condition = "clear"
+	PartlyCloudy Condition = "partly_cloudy"
+	Cloudy       Condition = "cloudy"
+	Overcast     Condition = "overcast"
+	Fog          Condition = "fog"
+	Drizzle      Condition = "drizzle"
+	Rain         Condition = "rain"
+	Thunderstorm Condition = "thunderstorm"
+	Sleet        Condition = "sleet"
+	Snow         Condition = "snow"
+	Squall       Condition = "squall"
+)
+
+// emoji - значки условий днем и ночью
+var emoji = map[Condition][2]string{
+	Unknown:      {"🌡", "🌡"},
+	Clear:        {"☀️", "🌙"},
+	PartlyCloudy: {"🌤", "☁️"},
+	Cloudy:       {"⛅", "☁️"},
+	Overcast:     {"☁️", "☁️"},
+	Fog:          {"🌫", "🌫"},
+	Drizzle:      {"🌦", "🌧"},
+	Rain:         {"🌧", "🌧"},
+	Thunderstorm: {"⛈", "⛈"},
+	Sleet:        {"🌨", "🌨"},
+	Snow:         {"❄️", "❄️"},
+	Squall:       {"🌪", "🌪"},
+}
+
+// FromOpenWeather переводит код условий OpenWeather (https://openweathermap.org/weather-conditions)
+func FromOpenWeather(code int) Condition {
+	switch {
+	case code >= 200 && code < 300:
+		return Thunderstorm
+	case code >= 300 && code < 400:
+		return Drizzle
+	case code == 511:
+		// Ледяной дождь
+		return Sleet
+	case code >= 500 && code < 600:
+		return Rain
+	case code >= 611 && code <= 616:
+		// Мокрый снег и снег с дождем
+		return Sleet
+	case code >= 600 && code < 700:
+		return Snow
+	case code == 771 || code == 781:
+		// Шквал и смерч
+		return Squall
+	case code >= 700 && code < 800:
+		return Fog
+	case code == 800:
+		return Clear
+	case code == 801:
+		return PartlyCloudy
+	case code == 802:
+		return Cloudy
+	case code == 803 || code == 804:
+		return Overcast
+	default:
+		return Unknown
+	}
+}
+
+// Emoji возвращает значок условий; night выбирает ночной вариант
+func (c Condition) Emoji(night bool) string {
+	variants, ok := emoji[c]
+	if !ok {
+		variants = emoji[Unknown]
+	}
+
+	if night {
+		return variants[1]
+	}
+
+	return variants[0]
+}
+
+// IsRain сообщает, идет ли дождь (в том числе морось, гроза и дождь со снегом)
+func (c Condition) IsRain() bool {
+	return c == Drizzle || c == Rain || c == Thunderstorm || c == Sleet
+}
+
+// IsSnow сообщает, идет ли снег (в том числе с дождем)
+func (c Condition) IsSnow() bool {
+	return c == Snow || c == Sleet
+}
@@ -21,14 +21,35 @@ type Config struct {
 	// Timezone для планировщика (по умолчанию Europe/Moscow)
 	Timezone string `env:"TIMEZONE" envDefault:"Europe/Moscow"`
 
-	// Время отправки прогноза погоды (по умолчанию 07:00)
+	// Время отправки прогноза погоды по умолчанию (по умолчанию 07:00)
 	WeatherScheduleHour int `env:"WEATHER_SCHEDULE_HOUR" envDefault:"7"`
 
+	// Время отправки еженедельной и ежемесячной сводки (по умолчанию 19:00)
+	DigestScheduleHour int `env:"DIGEST_SCHEDULE_HOUR" envDefault:"19"`
+
 	// Город для прогноза погоды
 	City string `env:"CITY" envDefault:"Moscow"`
 
 	// Код страны для OpenWeather API
 	CountryCode string `env:"COUNTRY_CODE" envDefault:"RU"`
+
+	// Срок хранения истории наблюдений погоды в днях (0 - хранить без ограничений)
+	ObservationRetentionDays int `env:"OBSERVATION_RETENTION_DAYS" envDefault:"90"`
+
+	// Каталог с шаблонами сообщений (*.tmpl), переопределяющими встроенные (пусто - только встроенные)
+	TemplatesDir string `env:"TEMPLATES_DIR"`
+
+	// Лимит рассылки в секунду на все реплики вместе (Telegram допускает около 30 сообщений в секунду)
+	BroadcastRate int `env:"BROADCAST_RATE" envDefault:"30"`
+
+	// Количество задач очереди (отправок прогнозов, сводок и других), выполняемых одновременно на одной реплике
+	QueueWorkers int `env:"QUEUE_WORKERS" envDefault:"8"`
+
+	// Сколько минут после времени рассылки повторяется неудачная отправка прогноза (0 - без повторов)
+	DeliveryRetryMinutes int `env:"DELIVERY_RETRY_MINUTES" envDefault:"120"`
+
+	// Сколько минут после времени рассылки отправляются прогнозы, пропущенные, пока бот не работал (0 - не отправлять)
+	CatchUpMinutes int `env:"CATCH_UP_MINUTES" envDefault:"120"`
 }
 
 // Load загружает конфигурацию из переменных окружения
@@ -46,6 +67,30 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid weather schedule hour: %d (must be 0-23)", cfg.WeatherScheduleHour)
 	}
 
+	if cfg.DigestScheduleHour < 0 || cfg.DigestScheduleHour > 23 {
+		return nil, fmt.Errorf("invalid digest schedule hour: %d (must be 0-23)", cfg.DigestScheduleHour)
+	}
+
+	if cfg.ObservationRetentionDays < 0 {
+		return nil, fmt.Errorf("invalid observation retention: %d days (must be >= 0)", cfg.ObservationRetentionDays)
+	}
+
+	if cfg.BroadcastRate <= 0 {
+		return nil, fmt.Errorf("invalid broadcast rate: %d (must be > 0)", cfg.BroadcastRate)
+	}
+
+	if cfg.QueueWorkers <= 0 {
+		return nil, fmt.Errorf("invalid queue workers: %d (must be > 0)", cfg.QueueWorkers)
+	}
+
+	if cfg.DeliveryRetryMinutes < 0 {
+		return nil, fmt.Errorf("invalid delivery retry window: %d minutes (must be >= 0)", cfg.DeliveryRetryMinutes)
+	}
+
+	if cfg.CatchUpMinutes < 0 {
+		return nil, fmt.Errorf("invalid catch-up window: %d minutes (must be >= 0)", cfg.CatchUpMinutes)
+	}
+
 	return cfg, nil
 }
 
@@ -0,0 +1,339 @@
+package i18n
+
+// english - сообщения на английском языке
+var english = map[string]string{
+	"language.name": "English",
+
+	// Общие ошибки
+	"error.generic":            "Something went wrong. Please try again later.",
+	"error.registration":       "Registration failed. Please try again later.",
+	"error.not_registered":     "Please send /start to register first.",
+	"error.weather":            "Couldn't get the weather. Please try again later.",
+	"error.data":               "Couldn't get the data. Please try again later.",
+	"error.chart":              "Couldn't build the chart. Please try again later.",
+	"error.location_not_found": "Couldn't find that place. Check the name or coordinates.",
+
+	// Команды
+	"start.welcome": "👋 Welcome to DeepCake Bot!\n\n" +
+		"I'll send you the weather forecast every morning at %s.\n" +
+		"You can change the city, time, days, message layout and language in /settings.\n\n" +
+		"Commands:\n" +
+		"/weather - current weather (/weather Kazan - in another city)\n" +
+		"/chart - temperature and precipitation chart for 48 hours\n" +
+		"/sun - sunrise, sunset and moon phase\n" +
+		"/trip - trip: forecast for your destination\n" +
+		"/settings - settings\n" +
+		"/cancel - cancel input\n" +
+		"/stop - unsubscribe from all messages",
+	"start.welcome_back": "👋 Welcome back! Your subscriptions are on again with your previous settings, " +
+		"the forecast will come at %s. You can change them in /settings.",
+	"stop.done":              "You've unsubscribed from all messages. Bot commands still work, and /start brings the subscriptions back.",
+	"stop.already":           "You're already unsubscribed. Send /start to subscribe again.",
+	"weather.usage":          "Specify a city (/weather Kazan) or coordinates (/weather 55.75 37.61).",
+	"button.save_location":   "📍 Save as my city",
+	"location.saved":         "📍 %s saved as your city!",
+	"button.add_location":    "🗂 Add to my places",
+	"location.added":         "🗂 «%s» added to your places!",
+	"location.limit":         "You can save up to %d places. Remove some in /settings.",
+	"location.deleted":       "This place has already been removed.",
+	"chart.caption":          "📈 Temperature (%s) and precipitation (mm) in %s for the next 48 hours",
+	"sun.title":              "🌍 Sun and Moon in %s:",
+	"inline.current":         "Now in %s",
+	"inline.today":           "Today's forecast in %s",
+	"inline.days":            "Forecast for %d %s in %s",
+	"plural.days":            "day|days",
+	"duration.hours_minutes": "%d h %02d min",
+
+	// Сообщение с погодой
+	"weather.summary":    "%s: %s, %s",
+	"weather.feels_like": "Feels like: %s",
+	"weather.humidity":   "Humidity: %d%%",
+	"weather.wind":       "Wind speed: %s",
+	"weather.pressure":   "Pressure: %s",
+
+	"air.quality": "Air quality: %s (PM2.5 %.0f, PM10 %.0f µg/m³)",
+	"air.index.1": "good",
+	"air.index.2": "fair",
+	"air.index.3": "moderate",
+	"air.index.4": "poor",
+	"air.index.5": "very poor",
+
+	"clothing.very_cold":   "🧥 Very cold! Warm winter clothes, a hat, scarf and gloves are a must.",
+	"clothing.cold":        "❄️ Cold. A winter jacket and warm accessories (hat, gloves).",
+	"clothing.chilly":      "🧥 Chilly. A light coat, maybe a scarf.",
+	"clothing.cool":        "🧥 Cool weather. A light jacket or a hoodie.",
+	"clothing.comfortable": "👕 Comfortable temperature. Light clothes, no jacket needed.",
+	"clothing.hot":         "☀️ Hot! Light summer clothes, don't forget sunscreen.",
+	"clothing.rain":        "☔ It's raining - take an umbrella or a raincoat!",
+	"clothing.snow":        "❄️ It's snowing - dress warmer and be careful on the roads!",
+	"clothing.rain_window": "☔ Rain from %s to %s, %.0f%% (%.1f mm) - take an umbrella or a raincoat!",
+	"clothing.snow_window": "❄️ Snow from %s to %s, %.0f%% (%.1f mm) - dress warmer and be careful on the roads!",
+
+	// Дорога на работу
+	"commute.departure": "🚗 Leave %s: %s",
+	"commute.return":    "🏁 Return %s: %s",
+
+	"comparison.warmer":           "📈 %.0f° warmer than yesterday at this time",
+	"comparison.colder":           "📉 %.0f° colder than yesterday at this time",
+	"comparison.same_temperature": "↔️ Same temperature as yesterday at this time",
+	"comparison.wind_stronger":    "💨 Noticeably windier than yesterday (+%s)",
+	"comparison.wind_weaker":      "🍃 Noticeably calmer than yesterday (−%s)",
+	"comparison.rain_started":     "☔ Yesterday was dry, today it's raining",
+	"comparison.rain_stopped":     "🌂 Yesterday's rain has stopped",
+	"comparison.snow_started":     "🌨 No snow yesterday, but it's snowing today",
+	"comparison.snow_stopped":     "⛄ Yesterday's snowfall has stopped",
+
+	"alerts.title":       "⚠️ Heads up:",
+	"alert.ice":          "🧊 Below freezing - watch out for ice",
+	"alert.heat":         "🥵 Heat - drink more water and avoid the midday sun",
+	"alert.wind":         "🌬 Strong wind - be careful outside",
+	"alert.rain":         "🌧 It's raining now",
+	"alert.thunderstorm": "⛈ Thunderstorm - stay indoors if you can",
+	"alert.snow":         "🌨 It's snowing now",
+
+	// Солнце и Луна
+	"astro.polar_day":   "☀️ Polar day: the Sun doesn't set",
+	"astro.polar_night": "🌌 Polar night: the Sun doesn't rise",
+	"astro.sun":         "🌅 Sunrise: %s, 🌇 sunset: %s",
+	"astro.day_length":  "☀️ Day length: %s (%s)",
+	"astro.day_longer":  "+%d min vs yesterday",
+	"astro.day_shorter": "−%d min vs yesterday",
+	"astro.day_same":    "same as yesterday",
+	"astro.moon":        "%s Moon: %s, %.0f%% illuminated",
+	"moon.phase.0":      "new moon",
+	"moon.phase.1":      "waxing crescent",
+	"moon.phase.2":      "first quarter",
+	"moon.phase.3":      "waxing gibbous",
+	"moon.phase.4":      "full moon",
+	"moon.phase.5":      "waning gibbous",
+	"moon.phase.6":      "last quarter",
+	"moon.phase.7":      "waning crescent",
+
+	// Прогноз
+	"forecast.empty":         "No forecast for %s.",
+	"forecast.today":         "📅 Today's forecast in %s:",
+	"forecast.next_day":      "📅 Forecast for the next 24 hours in %s:",
+	"forecast.days":          "🗓 Forecast for %d %s in %s:",
+	"forecast.precipitation": ", precipitation %.1f mm (%.0f%%)",
+
+	// Сводки
+	"digest.weekly.title":            "📅 Weekly weather in %s",
+	"digest.weekly.past":             "Past week:",
+	"digest.weekly.totals":           "🌡 High: %+.1f°, low: %+.1f°\n☔ Rainy days: %d of %d",
+	"digest.weekly.no_observations":  "No observations saved for the past week.",
+	"digest.weekly.coming":           "Coming days:",
+	"digest.monthly.title":           "🗓 Monthly summary for %s: %s %d",
+	"digest.monthly.no_observations": "No observations saved for this month.",
+	"digest.monthly.warmest":         "🔥 Highest temperature: %+.1f° (%s)",
+	"digest.monthly.coldest":         "🥶 Lowest temperature: %+.1f° (%s)",
+	"digest.monthly.windiest":        "💨 Strongest wind: %s (%s)",
+	"digest.monthly.days":            "☔ Rainy days: %d, 🌨 snowy: %d of %d",
+	"digest.monthly.records":         "🏆 All-time records: %s",
+	"digest.monthly.record_warmest":  "highest temperature",
+	"digest.monthly.record_coldest":  "lowest temperature",
+	"digest.monthly.record_windiest": "strongest wind",
+
+	// Дни недели и месяцы
+	"weekday.0": "Sun",
+	"weekday.1": "Mon",
+	"weekday.2": "Tue",
+	"weekday.3": "Wed",
+	"weekday.4": "Thu",
+	"weekday.5": "Fri",
+	"weekday.6": "Sat",
+
+	"month.1":  "January",
+	"month.2":  "February",
+	"month.3":  "March",
+	"month.4":  "April",
+	"month.5":  "May",
+	"month.6":  "June",
+	"month.7":  "July",
+	"month.8":  "August",
+	"month.9":  "September",
+	"month.10": "October",
+	"month.11": "November",
+	"month.12": "December",
+
+	"days.every_day": "every day",
+	"days.workdays":  "on weekdays",
+	"days.weekends":  "on weekends",
+
+	// Настройки
+	"settings.main": "⚙️ <b>Settings</b>\n\n" +
+		"🔔 Morning forecast: <b>%s</b>\n" +
+		"📍 City: <b>%s</b>\n" +
+		"⏰ Time: <b>%s</b>\n" +
+		"📆 Days: <b>%s</b>\n" +
+		"📅 Sunday digest and monthly summary: <b>%s</b>\n" +
+		"🌐 Language: <b>%s</b>\n" +
+		"📏 Units: <b>%s</b>",
+	"settings.paused":           "paused, resumes on %s",
+	"settings.on":               "on",
+	"settings.off":              "off",
+	"settings.back":             "⬅️ Back",
+	"settings.weather_on":       "🔔 Turn on forecast",
+	"settings.weather_off":      "🔕 Turn off forecast",
+	"settings.weather_enabled":  "Morning forecast is on!",
+	"settings.weather_disabled": "Morning forecast is off.",
+	"settings.digest_on":        "📅 Subscribe to digest",
+	"settings.digest_off":       "📅 Unsubscribe from digest",
+	"settings.digest_enabled":   "You've subscribed to the digest!",
+	"settings.digest_disabled":  "You've unsubscribed from the digest.",
+	"settings.location":         "📍 City",
+	"settings.time":             "⏰ Time",
+	"settings.days":             "📆 Days",
+	"settings.sections":         "🧩 Sections",
+	"settings.alerts":           "⚠️ Alerts",
+	"settings.language":         "🌐 Language",
+	"settings.units":            "📏 Units",
+	"settings.places":           "🗂 My places",
+	"settings.commute":          "🚗 Commute",
+
+	"settings.location.text": "📍 Forecast city: <b>%s</b>\n\n" +
+		"Tap «Enter city» or send /weather &lt;city&gt; and tap «Save as my city».",
+	"settings.location.enter":      "✏️ Enter city",
+	"settings.location.reset":      "↩️ Default city (%s)",
+	"settings.location.reset_done": "City reset.",
+
+	"settings.time.text":  "⏰ Morning forecast time: <b>%s</b> (%s)\n\nChoose a new time:",
+	"settings.time.enter": "✏️ Other time",
+	"settings.time.reset": "↩️ Default (%s)",
+	"settings.time.set":   "⏰ The forecast will arrive at %s.",
+
+	"settings.days.text":         "📆 Morning forecast days: <b>%s</b>\n\nTap a day to turn it on or off:",
+	"settings.days.every_day":    "Every day",
+	"settings.days.workdays":     "Weekdays",
+	"settings.days.weekends":     "Weekends",
+	"settings.days.set":          "Forecast days: %s.",
+	"settings.days.at_least_one": "At least one day is required. To turn the forecast off, use the main screen.",
+
+	"settings.sections.text": "🧩 <b>Morning message sections</b>\n\n" +
+		"Order: <b>%s</b>\n" +
+		"📈 48-hour chart: <b>%s</b>\n\n" +
+		"Tap a section to turn it on or off, ⬆️ to move it up.",
+	"settings.sections.chart":        "📈 Chart",
+	"settings.sections.at_least_one": "At least one section is required.",
+
+	"section.summary":    "Summary",
+	"section.details":    "Details",
+	"section.clothing":   "Clothing",
+	"section.air":        "Air quality",
+	"section.astro":      "Sun and Moon",
+	"section.alerts":     "Alerts",
+	"section.comparison": "Compared to yesterday",
+	"section.commute":    "Commute",
+
+	"settings.alerts.text": "⚠️ <b>Alerts</b>\n\n" +
+		"The bot will add an alert to the morning message if one of the enabled rules fires:",
+	"alert_rule.frost":         "🧊 Frost and ice",
+	"alert_rule.heat":          "🥵 Heat",
+	"alert_rule.wind":          "🌬 Strong wind",
+	"alert_rule.precipitation": "🌧 Precipitation",
+
+	"settings.language.text": "🌐 Message language: <b>%s</b>\n\nChoose a language:",
+	"settings.language.auto": "Same as Telegram (%s)",
+	"settings.language.set":  "Language: %s.",
+
+	"settings.places.text": "🗂 <b>My places</b>\n\n" +
+		"Main city: <b>%s</b>\n" +
+		"Places marked ✅ are included in the morning message along with the main city. " +
+		"In /weather you can switch between them with buttons.",
+	"settings.places.empty":   "No saved places yet.",
+	"settings.places.add":     "➕ Add a place",
+	"settings.places.deleted": "«%s» removed.",
+
+	"settings.units.text": "📏 <b>Units</b>\n\n" +
+		"🌡 Temperature: <b>%s</b>\n" +
+		"💨 Wind: <b>%s</b>\n" +
+		"🔽 Pressure: <b>%s</b>\n\n" +
+		"Tap a unit to select it:",
+	"settings.units.set": "Units: %s.",
+
+	"settings.commute.text": "🚗 <b>Commute</b>: <b>%s</b>\n\n" +
+		"🏠 Home: <b>%s</b>, leaving at <b>%s</b>\n" +
+		"🏢 Work: <b>%s</b>, returning at <b>%s</b>\n\n" +
+		"The morning message will show the weather at home when you leave and at work when you return (%s). " +
+		"Home and work can be your main city or any of your places.",
+	"settings.commute.toggle":        "Commute forecast",
+	"settings.commute.home":          "🏠 %s",
+	"settings.commute.work":          "🏢 %s",
+	"settings.commute.departure":     "🚗 Leave %s",
+	"settings.commute.return":        "🏁 Return %s",
+	"settings.commute.enabled":       "Commute forecast enabled!",
+	"settings.commute.disabled":      "Commute forecast disabled.",
+	"settings.commute.departure_set": "🚗 Leaving at %s.",
+	"settings.commute.return_set":    "🏁 Returning at %s.",
+
+	// Единицы измерения
+	"unit.c":    "°C",
+	"unit.f":    "°F",
+	"unit.ms":   "m/s",
+	"unit.kmh":  "km/h",
+	"unit.mph":  "mph",
+	"unit.kn":   "kn",
+	"unit.hpa":  "hPa",
+	"unit.mmhg": "mmHg",
+
+	// Пауза рассылки
+	"button.pause_week":     "⏸ Week",
+	"button.pause_tomorrow": "🔕 Tomorrow",
+	"button.resume":         "▶️ Resume forecasts",
+	"pause.set":             "⏸ Forecasts paused, they will resume on %s.",
+	"pause.skipped":         "🔕 The forecast for %s is skipped, the next ones will arrive as usual.",
+	"pause.resumed":         "▶️ Forecasts resumed!",
+	"delivery.late":         "⏰ This forecast was due at %s - sorry it's late!",
+
+	// Поездки
+	"trip.info": "✈️ Trip: %s\n📅 %s\n🕒 Local time: %s\n\n" +
+		"During the trip the morning forecast is for your destination, at your usual delivery time " +
+		"in local time. When the trip ends, the forecast is for home again.",
+	"button.change_trip": "✏️ Change",
+	"button.cancel_trip": "❌ Cancel trip",
+	"trip.saved": "✈️ Trip to %s (%s, %s) saved!\n\n" +
+		"A few days before you leave I'll send a packing forecast, and during the trip the morning forecast " +
+		"will be for your destination in local time. After that it will be for %s again.",
+	"trip.cancelled":           "Trip cancelled. The forecast will be for %s.",
+	"trip.ended":               "🏠 Welcome back! Your trip to %s is over, the forecast is for %s again.",
+	"trip.morning":             "✈️ Trip to %s, day %d of %d",
+	"trip.packing.title":       "🧳 Your trip to %s is coming up (%s). Forecast for the trip:",
+	"trip.packing.no_forecast": "The forecast for these dates isn't available yet - I'll send the morning forecast once the trip starts.",
+	"trip.packing.tips":        "What to pack:",
+	"trip.packing.umbrella":    "☔ An umbrella or a raincoat - rain is expected.",
+	"trip.packing.snow":        "🥾 Waterproof shoes - snow is expected.",
+
+	// Диалоги
+	"dialog.cancel_hint":       "Cancel input: /cancel",
+	"dialog.cancelled":         "Input cancelled.",
+	"dialog.nothing_to_cancel": "Nothing to cancel.",
+	"dialog.expired":           "⌛️ Timed out waiting for your reply, input cancelled. Start again in /settings.",
+	"dialog.error":             "Something went wrong. Try again or send /cancel.",
+	"dialog.yes":               "✅ Yes",
+	"dialog.no":                "❌ No",
+	"dialog.choose":            "Choose «%s» or «%s».",
+
+	"dialog.location.query": "📍 Send a city name or coordinates as «latitude longitude», " +
+		"for example: Kazan or 55.75 37.61",
+	"dialog.location.invalid":   "Send a city name or coordinates, for example: 55.75 37.61",
+	"dialog.location.not_found": "Couldn't find that place. Check the name and try again.",
+	"dialog.location.confirm":   "Found: %s (%s, %s). Save as your city?",
+
+	"dialog.place.confirm":      "Found: %s (%s, %s). Add to your places?",
+	"dialog.place.name":         "What should this place be called? For example: Home, Office, Cottage. Or keep «%s».",
+	"dialog.place.keep_name":    "✅ Keep the name",
+	"dialog.place.name_invalid": "The name must be at most %d characters long.",
+
+	"dialog.time.query":   "⏰ Send the forecast time as HH:MM (%s), for example 06:45",
+	"dialog.time.invalid": "Couldn't read the time. Send it as HH:MM, for example 06:45",
+
+	"dialog.commute.departure": "🚗 Send the time you leave home as HH:MM (%s), for example 08:30",
+	"dialog.commute.return":    "🏁 Send the time you leave work as HH:MM (%s), for example 19:00",
+
+	"dialog.trip.query": "✈️ Where are you going? Send a city name or coordinates as «latitude longitude», " +
+		"for example: Sochi or 43.58 39.72",
+	"dialog.trip.confirm":       "Found: %s (%s, %s). Is this your destination?",
+	"dialog.trip.dates":         "📅 Send the dates of your trip to %s (DD.MM), for example: 12.03-20.03 or 12.03.2027-20.03.2027",
+	"dialog.trip.dates_invalid": "Couldn't read the dates. Send them as DD.MM-DD.MM, for example 12.03-20.03",
+	"dialog.trip.dates_past":    "This trip is already over. Send future dates.",
+	"dialog.trip.too_long":      "A trip can last up to %d days.",
+}
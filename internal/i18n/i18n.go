@@ -0,0 +1,216 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lang - язык сообщений бота (код ISO 639-1)
+type Lang string
+
+const (
+	Russian Lang = "ru"
+	English Lang = "en"
+
+	// Default - язык, на котором отображаются сообщения, если перевода нет
+	Default = Russian
+)
+
+// pluralSeparator разделяет формы слова в сообщениях для Plural
+const pluralSeparator = "|"
+
+// Languages - поддерживаемые языки в порядке отображения
+var Languages = []Lang{Russian, English}
+
+// catalogs - сообщения по языкам
+var catalogs = map[Lang]map[string]string{
+	Russian: russian,
+	English: english,
+}
+
+// pluralForms - количество форм слова в сообщениях для Plural
+var pluralForms = map[Lang]int{
+	Russian: 3,
+	English: 2,
+}
+
+// Parse разбирает код языка ("en", "en-US") и проверяет, что язык поддерживается
+func Parse(code string) (Lang, bool) {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+
+	lang := Lang(code)
+	if _, ok := catalogs[lang]; !ok {
+		return "", false
+	}
+
+	return lang, true
+}
+
+// FromTelegram выбирает язык по language_code пользователя Telegram.
+// Если код не указан, используется язык по умолчанию, если язык не поддерживается - английский.
+func FromTelegram(code string) Lang {
+	if code == "" {
+		return Default
+	}
+
+	if lang, ok := Parse(code); ok {
+		return lang
+	}
+
+	return English
+}
+
+// Name возвращает название языка на самом этом языке
+func (l Lang) Name() string {
+	return l.T("language.name")
+}
+
+// T возвращает сообщение key на языке l, подставляя args через fmt.Sprintf.
+// Если перевода нет, используется язык по умолчанию, а если нет и его - сам ключ.
+func (l Lang) T(key string, args ...any) string {
+	message := lookup(l, key)
+
+	if len(args) == 0 {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
+
+// Plural возвращает форму слова key, согласованную с числом n: "1 день", "2 дня", "5 дней".
+// Формы в каталоге разделены символом "|".
+func (l Lang) Plural(key string, n int) string {
+	lang := l
+	if _, ok := catalogs[lang][key]; !ok {
+		lang = Default
+	}
+
+	forms := strings.Split(lookup(lang, key), pluralSeparator)
+
+	index := pluralIndex(lang, n)
+	if index >= len(forms) {
+		index = len(forms) - 1
+	}
+
+	return forms[index]
+}
+
+// lookup ищет сообщение в каталоге языка l, затем в каталоге языка по умолчанию
+func lookup(l Lang, key string) string {
+	if message, ok := catalogs[l][key]; ok {
+		return message
+	}
+
+	if message, ok := catalogs[Default][key]; ok {
+		return message
+	}
+
+	return key
+}
+
+// pluralIndex возвращает номер формы слова для числа n по правилам языка
+func pluralIndex(l Lang, n int) int {
+	if n < 0 {
+		n = -n
+	}
+
+	switch l {
+	case Russian:
+		n %= 100
+
+		switch {
+		case n%10 == 1 && n != 11:
+			return 0
+		case n%10 >= 2 && n%10 <= 4 && (n < 12 || n > 14):
+			return 1
+		default:
+			return 2
+		}
+	default:
+		if n == 1 {
+			return 0
+		}
+
+		return 1
+	}
+}
+
+// Validate проверяет каталоги: каждый ключ должен быть переведен на все языки
+// с теми же параметрами подстановки, а у слов для Plural должно быть нужное число форм
+func Validate() error {
+	keys := make(map[string]struct{})
+	for _, catalog := range catalogs {
+		for key := range catalog {
+			keys[key] = struct{}{}
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var errs []error
+
+	for _, key := range sorted {
+		reference, hasReference := catalogs[Default][key]
+
+		for _, lang := range Languages {
+			message, ok := catalogs[lang][key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: missing key %q", lang, key))
+				continue
+			}
+
+			if hasReference && verbs(message) != verbs(reference) {
+				errs = append(
+					errs,
+					fmt.Errorf("%s: key %q has verbs %q, expected %q", lang, key, verbs(message), verbs(reference)),
+				)
+			}
+
+			if strings.HasPrefix(key, "plural.") {
+				if forms := len(strings.Split(message, pluralSeparator)); forms != pluralForms[lang] {
+					errs = append(
+						errs,
+						fmt.Errorf("%s: key %q has %d plural forms, expected %d", lang, key, forms, pluralForms[lang]),
+					)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// verbs возвращает последовательность глаголов форматирования сообщения, например "%s%d"
+func verbs(message string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(message); i++ {
+		if message[i] != '%' {
+			continue
+		}
+
+		j := i + 1
+		for j < len(message) && strings.IndexByte("+-# 0123456789.", message[j]) >= 0 {
+			j++
+		}
+		if j >= len(message) {
+			break
+		}
+
+		if message[j] != '%' {
+			b.WriteString(message[i : j+1])
+		}
+		i = j
+	}
+
+	return b.String()
+}
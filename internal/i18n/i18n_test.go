@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Fatalf("message catalogs are inconsistent:\n%v", err)
+	}
+}
+
+// withCatalogs подменяет каталоги сообщений на время теста
+func withCatalogs(t *testing.T, replacement map[Lang]map[string]string) {
+	t.Helper()
+
+	original := catalogs
+	catalogs = replacement
+	t.Cleanup(
+		func() {
+			catalogs = original
+		},
+	)
+}
+
+func TestValidateReportsBrokenCatalogs(t *testing.T) {
+	tests := []struct {
+		name    string
+		ru, en  map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing key",
+			ru:      map[string]string{"greeting": "Привет", "farewell": "Пока"},
+			en:      map[string]string{"greeting": "Hello"},
+			wantErr: `en: missing key "farewell"`,
+		},
+		{
+			name:    "key missing in default language",
+			ru:      map[string]string{"greeting": "Привет"},
+			en:      map[string]string{"greeting": "Hello", "farewell": "Bye"},
+			wantErr: `ru: missing key "farewell"`,
+		},
+		{
+			name:    "verbs mismatch",
+			ru:      map[string]string{"temperature": "%s: %.0f°"},
+			en:      map[string]string{"temperature": "%s: %d°"},
+			wantErr: `en: key "temperature" has verbs`,
+		},
+		{
+			name:    "plural forms",
+			ru:      map[string]string{"plural.days": "день|дня|дней"},
+			en:      map[string]string{"plural.days": "day"},
+			wantErr: `en: key "plural.days" has 1 plural forms, expected 2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				withCatalogs(t, map[Lang]map[string]string{Russian: tt.ru, English: tt.en})
+
+				err := Validate()
+				if err == nil {
+					t.Fatalf("Validate() = nil, want error containing %q", tt.wantErr)
+				}
+
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,339 @@
+package i18n
+
+// russian - сообщения на русском языке
+var russian = map[string]string{
+	"language.name": "Русский",
+
+	// Общие ошибки
+	"error.generic":            "Произошла ошибка. Попробуйте позже.",
+	"error.registration":       "Произошла ошибка при регистрации. Попробуйте позже.",
+	"error.not_registered":     "Сначала отправьте команду /start для регистрации.",
+	"error.weather":            "Не удалось получить погоду. Попробуйте позже.",
+	"error.data":               "Не удалось получить данные. Попробуйте позже.",
+	"error.chart":              "Не удалось построить график. Попробуйте позже.",
+	"error.location_not_found": "Не удалось найти такое место. Проверьте название или координаты.",
+
+	// Команды
+	"start.welcome": "👋 Добро пожаловать в DeepCake Bot!\n\n" +
+		"Я буду отправлять вам прогноз погоды каждое утро в %s.\n" +
+		"Город, время, дни, состав сообщения и язык можно изменить в /settings.\n\n" +
+		"Доступные команды:\n" +
+		"/weather - погода сейчас (/weather Kazan - в другом городе)\n" +
+		"/chart - график температуры и осадков на 48 часов\n" +
+		"/sun - восход, закат и фаза Луны\n" +
+		"/trip - поездка: прогноз для места поездки\n" +
+		"/settings - настройки\n" +
+		"/cancel - отменить ввод\n" +
+		"/stop - отписаться от всех рассылок",
+	"start.welcome_back": "👋 С возвращением! Рассылки снова включены с прежними настройками, " +
+		"прогноз придет в %s. Изменить их можно в /settings.",
+	"stop.done":              "Вы отписались от всех рассылок. Команды бота по-прежнему работают, а вернуть рассылки можно командой /start.",
+	"stop.already":           "Вы уже отписаны от рассылок. Чтобы вернуть их, отправьте /start.",
+	"weather.usage":          "Укажите город (/weather Kazan) или координаты (/weather 55.75 37.61).",
+	"button.save_location":   "📍 Сохранить как мой город",
+	"location.saved":         "📍 Город %s сохранен!",
+	"button.add_location":    "🗂 Добавить в мои места",
+	"location.added":         "🗂 Место «%s» добавлено!",
+	"location.limit":         "Можно сохранить не больше %d мест. Удалите лишние в /settings.",
+	"location.deleted":       "Это место уже удалено.",
+	"chart.caption":          "📈 Температура (%s) и осадки (мм) в %s на ближайшие 48 часов",
+	"sun.title":              "🌍 Солнце и Луна в %s:",
+	"inline.current":         "Сейчас в %s",
+	"inline.today":           "Прогноз на сегодня в %s",
+	"inline.days":            "Прогноз на %d %s в %s",
+	"plural.days":            "день|дня|дней",
+	"duration.hours_minutes": "%d ч %02d мин",
+
+	// Сообщение с погодой
+	"weather.summary":    "%s: %s, %s",
+	"weather.feels_like": "Ощущается как: %s",
+	"weather.humidity":   "Влажность: %d%%",
+	"weather.wind":       "Скорость ветра: %s",
+	"weather.pressure":   "Давление: %s",
+
+	"air.quality": "Качество воздуха: %s (PM2.5 %.0f, PM10 %.0f мкг/м³)",
+	"air.index.1": "хорошее",
+	"air.index.2": "удовлетворительное",
+	"air.index.3": "умеренное",
+	"air.index.4": "плохое",
+	"air.index.5": "очень плохое",
+
+	"clothing.very_cold":   "🧥 Очень холодно! Теплая зимняя одежда, шапка, шарф, перчатки обязательны.",
+	"clothing.cold":        "❄️ Холодно. Зимняя куртка, теплые аксессуары (шапка, перчатки).",
+	"clothing.chilly":      "🧥 Прохладно. Демисезонная куртка, можно добавить шарф.",
+	"clothing.cool":        "🧥 Прохладная погода. Легкая куртка или толстовка.",
+	"clothing.comfortable": "👕 Комфортная температура. Легкая одежда, можно без куртки.",
+	"clothing.hot":         "☀️ Жарко! Легкая летняя одежда, не забудьте солнцезащитные средства.",
+	"clothing.rain":        "☔ Сейчас идет дождь - возьмите зонт или дождевик!",
+	"clothing.snow":        "❄️ Сейчас идет снег - одевайтесь теплее и будьте осторожны на дорогах!",
+	"clothing.rain_window": "☔ Дождь с %s до %s, %.0f%% (%.1f мм) - возьмите зонт или дождевик!",
+	"clothing.snow_window": "❄️ Снег с %s до %s, %.0f%% (%.1f мм) - одевайтесь теплее и будьте осторожны на дорогах!",
+
+	// Дорога на работу
+	"commute.departure": "🚗 Выезд %s: %s",
+	"commute.return":    "🏁 Возвращение %s: %s",
+
+	"comparison.warmer":           "📈 На %.0f° теплее, чем вчера в это же время",
+	"comparison.colder":           "📉 На %.0f° холоднее, чем вчера в это же время",
+	"comparison.same_temperature": "↔️ Температура такая же, как вчера в это же время",
+	"comparison.wind_stronger":    "💨 Ветер заметно сильнее, чем вчера (+%s)",
+	"comparison.wind_weaker":      "🍃 Ветер заметно слабее, чем вчера (−%s)",
+	"comparison.rain_started":     "☔ Вчера было сухо, а сегодня дождь",
+	"comparison.rain_stopped":     "🌂 Вчерашний дождь закончился",
+	"comparison.snow_started":     "🌨 Вчера снега не было, а сегодня идет",
+	"comparison.snow_stopped":     "⛄ Вчерашний снегопад закончился",
+
+	"alerts.title":       "⚠️ Внимание:",
+	"alert.ice":          "🧊 Температура ниже нуля - возможен гололед",
+	"alert.heat":         "🥵 Жара - пейте больше воды и избегайте солнца днем",
+	"alert.wind":         "🌬 Сильный ветер - будьте осторожны на улице",
+	"alert.rain":         "🌧 Сейчас идет дождь",
+	"alert.thunderstorm": "⛈ Гроза - по возможности оставайтесь в помещении",
+	"alert.snow":         "🌨 Сейчас идет снег",
+
+	// Солнце и Луна
+	"astro.polar_day":   "☀️ Полярный день: Солнце не заходит",
+	"astro.polar_night": "🌌 Полярная ночь: Солнце не восходит",
+	"astro.sun":         "🌅 Восход: %s, 🌇 закат: %s",
+	"astro.day_length":  "☀️ Долгота дня: %s (%s)",
+	"astro.day_longer":  "+%d мин к вчерашнему",
+	"astro.day_shorter": "−%d мин к вчерашнему",
+	"astro.day_same":    "как вчера",
+	"astro.moon":        "%s Луна: %s, освещенность %.0f%%",
+	"moon.phase.0":      "новолуние",
+	"moon.phase.1":      "растущий серп",
+	"moon.phase.2":      "первая четверть",
+	"moon.phase.3":      "растущая Луна",
+	"moon.phase.4":      "полнолуние",
+	"moon.phase.5":      "убывающая Луна",
+	"moon.phase.6":      "последняя четверть",
+	"moon.phase.7":      "убывающий серп",
+
+	// Прогноз
+	"forecast.empty":         "Нет прогноза для %s.",
+	"forecast.today":         "📅 Прогноз на сегодня в %s:",
+	"forecast.next_day":      "📅 Прогноз на ближайшие сутки в %s:",
+	"forecast.days":          "🗓 Прогноз на %d %s в %s:",
+	"forecast.precipitation": ", осадки %.1f мм (%.0f%%)",
+
+	// Сводки
+	"digest.weekly.title":            "📅 Погода за неделю в %s",
+	"digest.weekly.past":             "Прошедшая неделя:",
+	"digest.weekly.totals":           "🌡 Максимум: %+.1f°, минимум: %+.1f°\n☔ Дождливых дней: %d из %d",
+	"digest.weekly.no_observations":  "За прошедшую неделю нет сохраненных наблюдений.",
+	"digest.weekly.coming":           "Ближайшие дни:",
+	"digest.monthly.title":           "🗓 Итоги месяца в %s: %s %d",
+	"digest.monthly.no_observations": "За этот месяц нет сохраненных наблюдений.",
+	"digest.monthly.warmest":         "🔥 Самая высокая температура: %+.1f° (%s)",
+	"digest.monthly.coldest":         "🥶 Самая низкая температура: %+.1f° (%s)",
+	"digest.monthly.windiest":        "💨 Самый сильный ветер: %s (%s)",
+	"digest.monthly.days":            "☔ Дождливых дней: %d, 🌨 снежных: %d из %d",
+	"digest.monthly.records":         "🏆 Рекорды за всю историю наблюдений: %s",
+	"digest.monthly.record_warmest":  "самая высокая температура",
+	"digest.monthly.record_coldest":  "самая низкая температура",
+	"digest.monthly.record_windiest": "самый сильный ветер",
+
+	// Дни недели и месяцы
+	"weekday.0": "вс",
+	"weekday.1": "пн",
+	"weekday.2": "вт",
+	"weekday.3": "ср",
+	"weekday.4": "чт",
+	"weekday.5": "пт",
+	"weekday.6": "сб",
+
+	"month.1":  "январь",
+	"month.2":  "февраль",
+	"month.3":  "март",
+	"month.4":  "апрель",
+	"month.5":  "май",
+	"month.6":  "июнь",
+	"month.7":  "июль",
+	"month.8":  "август",
+	"month.9":  "сентябрь",
+	"month.10": "октябрь",
+	"month.11": "ноябрь",
+	"month.12": "декабрь",
+
+	"days.every_day": "каждый день",
+	"days.workdays":  "по будням",
+	"days.weekends":  "по выходным",
+
+	// Настройки
+	"settings.main": "⚙️ <b>Настройки</b>\n\n" +
+		"🔔 Утренняя рассылка: <b>%s</b>\n" +
+		"📍 Город: <b>%s</b>\n" +
+		"⏰ Время: <b>%s</b>\n" +
+		"📆 Дни: <b>%s</b>\n" +
+		"📅 Сводка по воскресеньям и итоги месяца: <b>%s</b>\n" +
+		"🌐 Язык: <b>%s</b>\n" +
+		"📏 Единицы: <b>%s</b>",
+	"settings.paused":           "на паузе, снова с %s",
+	"settings.on":               "вкл",
+	"settings.off":              "выкл",
+	"settings.back":             "⬅️ Назад",
+	"settings.weather_on":       "🔔 Включить рассылку",
+	"settings.weather_off":      "🔕 Выключить рассылку",
+	"settings.weather_enabled":  "Рассылка включена!",
+	"settings.weather_disabled": "Рассылка выключена.",
+	"settings.digest_on":        "📅 Подписаться на сводку",
+	"settings.digest_off":       "📅 Отписаться от сводки",
+	"settings.digest_enabled":   "Вы подписались на сводку!",
+	"settings.digest_disabled":  "Вы отписались от сводки.",
+	"settings.location":         "📍 Город",
+	"settings.time":             "⏰ Время",
+	"settings.days":             "📆 Дни",
+	"settings.sections":         "🧩 Разделы",
+	"settings.alerts":           "⚠️ Предупреждения",
+	"settings.language":         "🌐 Язык",
+	"settings.units":            "📏 Единицы",
+	"settings.places":           "🗂 Мои места",
+	"settings.commute":          "🚗 Дорога на работу",
+
+	"settings.location.text": "📍 Город прогноза: <b>%s</b>\n\n" +
+		"Нажмите «Ввести город» или отправьте /weather &lt;город&gt; и нажмите «Сохранить как мой город».",
+	"settings.location.enter":      "✏️ Ввести город",
+	"settings.location.reset":      "↩️ Город по умолчанию (%s)",
+	"settings.location.reset_done": "Город сброшен.",
+
+	"settings.time.text":  "⏰ Время утренней рассылки: <b>%s</b> (%s)\n\nВыберите новое время:",
+	"settings.time.enter": "✏️ Другое время",
+	"settings.time.reset": "↩️ По умолчанию (%s)",
+	"settings.time.set":   "⏰ Прогноз будет приходить в %s.",
+
+	"settings.days.text":         "📆 Дни утренней рассылки: <b>%s</b>\n\nНажмите на день, чтобы включить или выключить его:",
+	"settings.days.every_day":    "Каждый день",
+	"settings.days.workdays":     "Будни",
+	"settings.days.weekends":     "Выходные",
+	"settings.days.set":          "Дни рассылки: %s.",
+	"settings.days.at_least_one": "Нужен хотя бы один день. Чтобы отключить рассылку, используйте главный экран.",
+
+	"settings.sections.text": "🧩 <b>Разделы утреннего сообщения</b>\n\n" +
+		"Порядок: <b>%s</b>\n" +
+		"📈 График на 48 часов: <b>%s</b>\n\n" +
+		"Нажмите на раздел, чтобы включить или выключить его, ⬆️ - чтобы поднять выше.",
+	"settings.sections.chart":        "📈 График",
+	"settings.sections.at_least_one": "Нужен хотя бы один раздел.",
+
+	"section.summary":    "Кратко",
+	"section.details":    "Подробности",
+	"section.clothing":   "Одежда",
+	"section.air":        "Качество воздуха",
+	"section.astro":      "Солнце и Луна",
+	"section.alerts":     "Предупреждения",
+	"section.comparison": "Сравнение со вчера",
+	"section.commute":    "Дорога",
+
+	"settings.alerts.text": "⚠️ <b>Предупреждения</b>\n\n" +
+		"Бот добавит предупреждение в утреннее сообщение, если сработает одно из включенных правил:",
+	"alert_rule.frost":         "🧊 Заморозки и гололед",
+	"alert_rule.heat":          "🥵 Жара",
+	"alert_rule.wind":          "🌬 Сильный ветер",
+	"alert_rule.precipitation": "🌧 Осадки",
+
+	"settings.language.text": "🌐 Язык сообщений: <b>%s</b>\n\nВыберите язык:",
+	"settings.language.auto": "Как в Telegram (%s)",
+	"settings.language.set":  "Язык: %s.",
+
+	"settings.places.text": "🗂 <b>Мои места</b>\n\n" +
+		"Основной город: <b>%s</b>\n" +
+		"Места, отмеченные ✅, входят в утреннее сообщение вместе с основным городом. " +
+		"В /weather между ними можно переключаться кнопками.",
+	"settings.places.empty":   "Сохраненных мест пока нет.",
+	"settings.places.add":     "➕ Добавить место",
+	"settings.places.deleted": "Место «%s» удалено.",
+
+	"settings.units.text": "📏 <b>Единицы измерения</b>\n\n" +
+		"🌡 Температура: <b>%s</b>\n" +
+		"💨 Ветер: <b>%s</b>\n" +
+		"🔽 Давление: <b>%s</b>\n\n" +
+		"Нажмите на единицу, чтобы выбрать ее:",
+	"settings.units.set": "Единицы: %s.",
+
+	"settings.commute.text": "🚗 <b>Дорога на работу</b>: <b>%s</b>\n\n" +
+		"🏠 Дом: <b>%s</b>, выезд в <b>%s</b>\n" +
+		"🏢 Работа: <b>%s</b>, возвращение в <b>%s</b>\n\n" +
+		"Утреннее сообщение покажет погоду у дома при выезде и у работы при возвращении (%s). " +
+		"Дом и работу можно выбрать из основного города и ваших мест.",
+	"settings.commute.toggle":        "Прогноз на дорогу",
+	"settings.commute.home":          "🏠 %s",
+	"settings.commute.work":          "🏢 %s",
+	"settings.commute.departure":     "🚗 Выезд %s",
+	"settings.commute.return":        "🏁 Возвращение %s",
+	"settings.commute.enabled":       "Прогноз на дорогу включен!",
+	"settings.commute.disabled":      "Прогноз на дорогу выключен.",
+	"settings.commute.departure_set": "🚗 Время выезда: %s.",
+	"settings.commute.return_set":    "🏁 Время возвращения: %s.",
+
+	// Единицы измерения
+	"unit.c":    "°C",
+	"unit.f":    "°F",
+	"unit.ms":   "м/с",
+	"unit.kmh":  "км/ч",
+	"unit.mph":  "миль/ч",
+	"unit.kn":   "уз",
+	"unit.hpa":  "гПа",
+	"unit.mmhg": "мм рт. ст.",
+
+	// Пауза рассылки
+	"button.pause_week":     "⏸ Неделя",
+	"button.pause_tomorrow": "🔕 Завтра",
+	"button.resume":         "▶️ Возобновить рассылку",
+	"pause.set":             "⏸ Рассылка на паузе, прогнозы снова начнут приходить с %s.",
+	"pause.skipped":         "🔕 Прогноз на %s не придет, дальше рассылка продолжится как обычно.",
+	"pause.resumed":         "▶️ Рассылка возобновлена!",
+	"delivery.late":         "⏰ Прогноз на %s приходит с опозданием - извините!",
+
+	// Поездки
+	"trip.info": "✈️ Поездка: %s\n📅 %s\n🕒 Местное время: %s\n\n" +
+		"Во время поездки утренний прогноз приходит для места поездки в ваше обычное время рассылки " +
+		"по местному времени. Когда поездка закончится, прогноз снова будет приходить для дома.",
+	"button.change_trip": "✏️ Изменить",
+	"button.cancel_trip": "❌ Отменить поездку",
+	"trip.saved": "✈️ Поездка в %s (%s, %s) сохранена!\n\n" +
+		"За несколько дней до отъезда пришлю прогноз для сборов, а во время поездки утренний прогноз " +
+		"будет приходить для места поездки по местному времени. Потом прогноз снова будет для %s.",
+	"trip.cancelled":           "Поездка отменена. Прогноз будет приходить для %s.",
+	"trip.ended":               "🏠 С возвращением! Поездка в %s закончилась, прогноз снова приходит для %s.",
+	"trip.morning":             "✈️ Поездка в %s, день %d из %d",
+	"trip.packing.title":       "🧳 Скоро поездка в %s (%s). Прогноз на дни поездки:",
+	"trip.packing.no_forecast": "Прогноз на эти даты пока недоступен - пришлю утренний прогноз, когда поездка начнется.",
+	"trip.packing.tips":        "Что взять с собой:",
+	"trip.packing.umbrella":    "☔ Зонт или дождевик - ожидаются дожди.",
+	"trip.packing.snow":        "🥾 Непромокаемую обувь - ожидается снег.",
+
+	// Диалоги
+	"dialog.cancel_hint":       "Отменить ввод: /cancel",
+	"dialog.cancelled":         "Ввод отменен.",
+	"dialog.nothing_to_cancel": "Нечего отменять.",
+	"dialog.expired":           "⌛️ Время ожидания ответа истекло, ввод отменен. Начните заново в /settings.",
+	"dialog.error":             "Произошла ошибка. Попробуйте еще раз или отправьте /cancel.",
+	"dialog.yes":               "✅ Да",
+	"dialog.no":                "❌ Нет",
+	"dialog.choose":            "Выберите «%s» или «%s».",
+
+	"dialog.location.query": "📍 Отправьте название города или координаты в формате «широта долгота», " +
+		"например: Казань или 55.75 37.61",
+	"dialog.location.invalid":   "Отправьте название города или координаты, например: 55.75 37.61",
+	"dialog.location.not_found": "Не удалось найти такое место. Проверьте название и попробуйте еще раз.",
+	"dialog.location.confirm":   "Нашел: %s (%s, %s). Сохранить как ваш город?",
+
+	"dialog.place.confirm":      "Нашел: %s (%s, %s). Добавить в ваши места?",
+	"dialog.place.name":         "Как назвать место? Например: Дом, Офис, Дача. Или оставьте «%s».",
+	"dialog.place.keep_name":    "✅ Оставить название",
+	"dialog.place.name_invalid": "Название должно быть не длиннее %d символов.",
+
+	"dialog.time.query":   "⏰ Отправьте время рассылки в формате ЧЧ:ММ (%s), например 06:45",
+	"dialog.time.invalid": "Не понял время. Отправьте его в формате ЧЧ:ММ, например 06:45",
+
+	"dialog.commute.departure": "🚗 Отправьте время выезда из дома в формате ЧЧ:ММ (%s), например 08:30",
+	"dialog.commute.return":    "🏁 Отправьте время возвращения с работы в формате ЧЧ:ММ (%s), например 19:00",
+
+	"dialog.trip.query": "✈️ Куда едете? Отправьте название города или координаты в формате «широта долгота», " +
+		"например: Сочи или 43.58 39.72",
+	"dialog.trip.confirm":       "Нашел: %s (%s, %s). Это место поездки?",
+	"dialog.trip.dates":         "📅 Отправьте даты поездки в %s, например: 12.03-20.03 или 12.03.2027-20.03.2027",
+	"dialog.trip.dates_invalid": "Не понял даты. Отправьте их в формате ДД.ММ-ДД.ММ, например 12.03-20.03",
+	"dialog.trip.dates_past":    "Эта поездка уже закончилась. Отправьте будущие даты.",
+	"dialog.trip.too_long":      "Поездка может длиться не больше %d дней.",
+}
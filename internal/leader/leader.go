@@ -0,0 +1,97 @@
+// Package leader выбирает ведущую среди реплик бота с помощью advisory-блокировки Postgres.
+// Ведущая реплика одна: она получает обновления от Telegram и выполняет задачи,
+// которые нельзя выполнять на нескольких репликах одновременно.
+package leader
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+const (
+	// lockKey - ключ advisory-блокировки ведущей реплики
+	lockKey int64 = 0x44434202
+	// checkInterval - как часто реплика пытается стать ведущей, а ведущая проверяет блокировку
+	checkInterval = 10 * time.Second
+	// unlockTimeout - сколько ждать снятия блокировки при потере лидерства
+	unlockTimeout = 5 * time.Second
+)
+
+// Elector выбирает ведущую реплику
+type Elector struct {
+	locks storage.LockRepository
+}
+
+// New создает выбор ведущей реплики
+func New(locks storage.LockRepository) *Elector {
+	return &Elector{locks: locks}
+}
+
+// Run пытается сделать реплику ведущей и, пока она остается ведущей, выполняет lead.
+// lead должна завершиться после отмены переданного ей контекста. Run возвращается после отмены ctx.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := e.locks.TryAdvisoryLock(ctx, lockKey)
+		if err != nil {
+			log.Printf("Failed to acquire leader lock: %v", err)
+		}
+
+		if lock != nil {
+			log.Println("This replica is the leader now")
+			e.lead(ctx, lock, lead)
+			log.Println("This replica is no longer the leader")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead выполняет lead, пока блокировка lock удерживается, и снимает ее после завершения
+func (e *Elector) lead(ctx context.Context, lock *storage.AdvisoryLock, lead func(ctx context.Context)) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-done:
+			break loop
+		case <-ticker.C:
+			// Если соединение с блокировкой оборвалось, ведущей может стать другая реплика
+			if err := lock.Check(ctx); err != nil {
+				log.Printf("Leader lock lost: %v", err)
+				break loop
+			}
+		}
+	}
+
+	cancel()
+	<-done
+
+	unlockCtx, cancelUnlock := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancelUnlock()
+
+	if err := lock.Unlock(unlockCtx); err != nil {
+		log.Printf("Failed to release leader lock: %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+// Package queue выполняет отложенные задачи, хранящиеся в Postgres: рассылки, напоминания,
+// повторные попытки. Задачи переживают перезапуск, выполняются с повторами и делятся между репликами.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+const (
+	// pollInterval - как часто очередь проверяет задачи, время которых наступило
+	pollInterval = time.Second
+	// lease - на сколько забранная задача скрывается от других реплик; если обработчик не успеет,
+	// задача выполнится повторно, а результат опоздавшей попытки не сохранится
+	lease = 10 * time.Minute
+	// retentionPeriod - сколько хранятся выполненные задачи и задачи в dead letter
+	retentionPeriod = 30 * 24 * time.Hour
+	// defaultBackoffBase, defaultBackoffMax - задержка перед повторной попыткой по умолчанию:
+	// удваивается с каждой попыткой, но не превышает максимум
+	defaultBackoffBase = time.Minute
+	defaultBackoffMax  = time.Hour
+)
+
+// Handler выполняет задачу. Ошибка означает, что задачу нужно повторить; ошибка, обернутая
+// в Permanent, - что повторять бесполезно и задача сразу отправляется в dead letter; ошибка,
+// обернутая в RetryAfter, - что повторить задачу можно не раньше заданной задержки.
+type Handler func(ctx context.Context, job *storage.Job) error
+
+// Options - настройки типа задач
+type Options struct {
+	// MaxAttempts - после скольких неудачных попыток задача отправляется в dead letter, 0 - без ограничения.
+	// Сохраняется в задаче при добавлении, если у задачи не задано свое ограничение.
+	MaxAttempts int
+	// Backoff возвращает задержку перед следующей попыткой после attempts неудачных;
+	// nil - экспоненциальная задержка от минуты до часа
+	Backoff func(attempts int) time.Duration
+	// RateLimit - общий для всех реплик лимит скорости выполнения задач, nil - без лимита
+	RateLimit *RateLimit
+}
+
+// RateLimit - лимит скорости выполнения задач на всех репликах вместе. Лимит хранится в базе,
+// поэтому не зависит от числа реплик; типы задач с одним названием лимита делят его между собой.
+type RateLimit struct {
+	// Name - название лимита
+	Name string
+	// Rate - сколько задач в секунду забирается на всех репликах вместе; подряд после простоя
+	// забирается не больше задач, чем начисляется за секунду
+	Rate float64
+}
+
+// handler - обработчик зарегистрированного типа задач
+type handler struct {
+	handle  Handler
+	options Options
+}
+
+// Queue выполняет задачи из очереди в пуле горутин
+type Queue struct {
+	jobs     storage.JobRepository
+	workers  int
+	handlers map[string]handler
+}
+
+// New создает очередь, выполняющую не больше workers задач одновременно
+func New(jobs storage.JobRepository, workers int) *Queue {
+	return &Queue{
+		jobs:     jobs,
+		workers:  max(workers, 1),
+		handlers: make(map[string]handler),
+	}
+}
+
+// Register регистрирует обработчик задач типа jobType. Вызывается до Run.
+func (q *Queue) Register(jobType string, options Options, handle Handler) {
+	if options.Backoff == nil {
+		options.Backoff = DefaultBackoff
+	}
+
+	q.handlers[jobType] = handler{handle: handle, options: options}
+}
+
+// NewJob создает задачу типа jobType с параметрами payload, которую нужно выполнить в runAt.
+// Непустой key делает добавление идемпотентным: задача с тем же ключом добавляется один раз.
+func NewJob(jobType, key string, runAt time.Time, payload interface{}) (storage.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return storage.Job{}, fmt.Errorf("failed to encode %s job payload: %w", jobType, err)
+	}
+
+	job := storage.Job{
+		Type:    jobType,
+		Payload: string(encoded),
+		Status:  storage.JobStatusPending,
+		RunAt:   runAt,
+	}
+	if key != "" {
+		job.Key = &key
+	}
+
+	return job, nil
+}
+
+// Decode разбирает параметры задачи в payload
+func Decode(job *storage.Job, payload interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), payload); err != nil {
+		return Permanent(fmt.Errorf("failed to decode %s job payload: %w", job.Type, err))
+	}
+
+	return nil
+}
+
+// Enqueue добавляет задачи в очередь
+func (q *Queue) Enqueue(ctx context.Context, jobs ...storage.Job) error {
+	for i := range jobs {
+		if h, ok := q.handlers[jobs[i].Type]; ok && jobs[i].MaxAttempts == 0 {
+			jobs[i].MaxAttempts = h.options.MaxAttempts
+		}
+	}
+
+	return q.jobs.EnqueueJobs(ctx, jobs)
+}
+
+// Run выполняет задачи зарегистрированных типов, пока не отменен ctx, и ждет завершения начатых.
+// Запускается на каждой реплике: задачи распределяются между ними.
+func (q *Queue) Run(ctx context.Context) {
+	// Задачи без лимита скорости забираются одним запросом, задачи с лимитом - по лимитам
+	var types []string
+	limited := make(map[string]*limitedTypes)
+	for jobType, h := range q.handlers {
+		if h.options.RateLimit == nil {
+			types = append(types, jobType)
+			continue
+		}
+
+		group, ok := limited[h.options.RateLimit.Name]
+		if !ok {
+			rate := h.options.RateLimit.Rate
+			group = &limitedTypes{
+				limit: storage.RateLimit{Name: h.options.RateLimit.Name, Rate: rate, Burst: max(int(rate), 1)},
+			}
+			limited[group.limit.Name] = group
+		}
+		group.types = append(group.types, jobType)
+	}
+
+	// slots ограничивает число выполняемых задач, finished будит цикл, когда задача завершилась
+	slots := make(chan struct{}, q.workers)
+	finished := make(chan struct{}, 1)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	start := func(jobs []storage.Job) {
+		for _, job := range jobs {
+			slots <- struct{}{}
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				defer func() {
+					<-slots
+					select {
+					case finished <- struct{}{}:
+					default:
+					}
+				}()
+
+				q.process(ctx, &job)
+			}()
+		}
+	}
+
+	for {
+		free := q.workers - len(slots)
+
+		claimed := 0
+		if free > 0 && len(types) > 0 {
+			jobs, err := q.jobs.ClaimDueJobs(ctx, types, time.Now(), free, lease)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to claim jobs: %v", err)
+			}
+
+			start(jobs)
+			claimed += len(jobs)
+		}
+
+		for _, group := range limited {
+			if claimed >= free {
+				break
+			}
+
+			jobs, err := q.jobs.ClaimRateLimitedJobs(ctx, group.limit, group.types, time.Now(), free-claimed, lease)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to claim %s rate limited jobs: %v", group.limit.Name, err)
+			}
+
+			start(jobs)
+			claimed += len(jobs)
+		}
+
+		// Если забраны все запрошенные задачи, готовые задачи, вероятно, еще остались
+		if claimed > 0 && claimed == free {
+			select {
+			case <-ctx.Done():
+				return
+			case <-finished:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-finished:
+		}
+	}
+}
+
+// limitedTypes - типы задач с общим лимитом скорости
+type limitedTypes struct {
+	limit storage.RateLimit
+	types []string
+}
+
+// process выполняет задачу и сохраняет результат
+func (q *Queue) process(ctx context.Context, job *storage.Job) {
+	h := q.handlers[job.Type]
+
+	err := h.handle(ctx, job)
+
+	// Результат сохраняется и при остановке, иначе выполненная задача повторится после lease
+	saveCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		if err := q.jobs.CompleteJob(saveCtx, job.ID, job.Attempts); err != nil {
+			logSaveError(job, "complete", err)
+		}
+		return
+	}
+
+	// При остановке задача не считается неудачной: она повторится после lease
+	if ctx.Err() != nil {
+		return
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || (job.MaxAttempts > 0 && job.Attempts >= job.MaxAttempts) {
+		log.Printf("%s job %d failed after %d attempts, moved to dead letter: %v", job.Type, job.ID, job.Attempts, err)
+
+		if err := q.jobs.MarkJobDead(saveCtx, job.ID, job.Attempts, err.Error()); err != nil {
+			logSaveError(job, "mark dead", err)
+		}
+		return
+	}
+
+	delay := h.options.Backoff(job.Attempts)
+
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) {
+		delay = retryAfter.delay
+	}
+
+	runAt := time.Now().Add(delay)
+	if err := q.jobs.RetryJob(saveCtx, job.ID, job.Attempts, err.Error(), runAt); err != nil {
+		logSaveError(job, "schedule retry of", err)
+	}
+}
+
+// logSaveError записывает в лог, что результат попытки задачи не сохранен; what - что не удалось сделать
+func logSaveError(job *storage.Job, what string, err error) {
+	if errors.Is(err, storage.ErrJobLeaseLost) {
+		log.Printf("%s job %d attempt %d outlived its lease, the result is not saved", job.Type, job.ID, job.Attempts)
+		return
+	}
+
+	log.Printf("Failed to %s %s job %d: %v", what, job.Type, job.ID, err)
+}
+
+// Prune удаляет выполненные задачи и задачи в dead letter старше срока хранения
+func (q *Queue) Prune(ctx context.Context) error {
+	deleted, err := q.jobs.DeleteJobsBefore(ctx, time.Now().Add(-retentionPeriod))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("Pruned %d jobs older than %s", deleted, retentionPeriod)
+	}
+
+	return nil
+}
+
+// DefaultBackoff возвращает экспоненциальную задержку от минуты до часа после attempts неудачных попыток
+func DefaultBackoff(attempts int) time.Duration {
+	return ExponentialBackoff(defaultBackoffBase, defaultBackoffMax)(attempts)
+}
+
+// ExponentialBackoff возвращает задержку base, удваивающуюся с каждой попыткой, но не больше limit
+func ExponentialBackoff(base, limit time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts && delay < limit; i++ {
+			delay *= 2
+		}
+
+		return min(delay, limit)
+	}
+}
+
+// permanentError - ошибка, после которой задачу не нужно повторять
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent помечает ошибку обработчика как окончательную: задача не повторяется
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// retryAfterError - ошибка, после которой задачу нужно повторить через заданное время
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// RetryAfter помечает ошибку обработчика как временную: задача повторяется через delay
+// вместо задержки Options.Backoff, например когда Telegram ответил FloodError
+func RetryAfter(err error, delay time.Duration) error {
+	return &retryAfterError{err: err, delay: delay}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deliveriesBatchSize - сколько записей журнала рассылки вставляется одним запросом
+const deliveriesBatchSize = 500
+
+// DeliveryRepository определяет интерфейс для работы с журналом рассылки
+type DeliveryRepository interface {
+	CreateDeliveries(ctx context.Context, deliveries []Delivery) error
+	GetDelivery(ctx context.Context, chatID int64, slot time.Time) (*Delivery, error)
+	GetFinishedDeliveryChats(ctx context.Context, slot time.Time) ([]int64, error)
+	ClaimDelivery(ctx context.Context, id uint, staleBefore time.Time) (bool, error)
+	SaveDeliveryMessages(ctx context.Context, id uint, messages string) error
+	MarkDeliveryProgress(ctx context.Context, id uint, sentParts, messageID int) error
+	MarkDeliverySent(ctx context.Context, id uint, messageID int) error
+	MarkDeliveryRetry(ctx context.Context, id uint, errorText string) error
+	MarkDeliveryFailed(ctx context.Context, id uint, errorText string) error
+	MarkDeliverySkipped(ctx context.Context, id uint, reason string) error
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// CreateDeliveries добавляет записи в журнал рассылки. Записи для пользователя и слота,
+// которые уже есть в журнале, не изменяются - так слот можно обработать повторно без дублей.
+func (s *PostgresStorage) CreateDeliveries(ctx context.Context, deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	result := s.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "chat_id"}, {Name: "slot"}},
+				DoNothing: true,
+			},
+		).
+		CreateInBatches(deliveries, deliveriesBatchSize)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to create deliveries: %w", result.Error)
+	}
+
+	return nil
+}
+
+// GetDelivery получает запись журнала рассылки пользователя chatID для слота slot
+func (s *PostgresStorage) GetDelivery(ctx context.Context, chatID int64, slot time.Time) (*Delivery, error) {
+	var delivery Delivery
+
+	result := s.db.WithContext(ctx).Where("chat_id = ? AND slot = ?", chatID, slot).Take(&delivery)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", result.Error)
+	}
+
+	return &delivery, nil
+}
+
+// GetFinishedDeliveryChats получает получателей, отправка которым в слот slot завершена:
+// прогноз отправлен, пропущен или окончательно не отправлен
+func (s *PostgresStorage) GetFinishedDeliveryChats(ctx context.Context, slot time.Time) ([]int64, error) {
+	var chatIDs []int64
+
+	result := s.db.WithContext(ctx).
+		Model(&Delivery{}).
+		Where("slot = ? AND status IN ?", slot, []string{DeliveryStatusSent, DeliveryStatusFailed, DeliveryStatusSkipped}).
+		Pluck("chat_id", &chatIDs)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get finished deliveries: %w", result.Error)
+	}
+
+	return chatIDs, nil
+}
+
+// ClaimDelivery отмечает, что прогноз отправляется. Забрать можно запись, ожидающую отправки,
+// или запись, отправка по которой не продвигалась с staleBefore, - обработчик, вероятно, упал.
+// Возвращает false, если запись уже отправлена или ее отправляет другой обработчик.
+func (s *PostgresStorage) ClaimDelivery(ctx context.Context, id uint, staleBefore time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).
+		Model(&Delivery{}).
+		Where("id = ?", id).
+		Where(
+			"status = ? OR (status = ? AND updated_at < ?)",
+			DeliveryStatusPending, DeliveryStatusSending, staleBefore,
+		).
+		Update("status", DeliveryStatusSending)
+
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim delivery: %w", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// MarkDeliveryProgress сохраняет, сколько сообщений прогноза отправлено; messageID - ID последнего из них
+func (s *PostgresStorage) MarkDeliveryProgress(ctx context.Context, id uint, sentParts, messageID int) error {
+	return s.updateDelivery(
+		ctx, id, "progress", map[string]interface{}{
+			"sent_parts": sentParts,
+			"message_id": messageID,
+		},
+	)
+}
+
+// SaveDeliveryMessages сохраняет сообщения прогноза (JSON), собранные перед первой отправкой
+func (s *PostgresStorage) SaveDeliveryMessages(ctx context.Context, id uint, messages string) error {
+	return s.updateDelivery(ctx, id, "messages", map[string]interface{}{"messages": messages})
+}
+
+// updateDelivery обновляет поля записи журнала рассылки; what используется в тексте ошибки
+func (s *PostgresStorage) updateDelivery(ctx context.Context, id uint, what string, fields map[string]interface{}) error {
+	result := s.db.WithContext(ctx).Model(&Delivery{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark delivery %s: %w", what, result.Error)
+	}
+
+	return nil
+}
+
+// MarkDeliverySent отмечает успешную отправку; messageID - ID последнего сообщения в Telegram
+func (s *PostgresStorage) MarkDeliverySent(ctx context.Context, id uint, messageID int) error {
+	return s.updateDelivery(
+		ctx, id, "sent", map[string]interface{}{
+			"status":     DeliveryStatusSent,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"error":      "",
+			"message_id": messageID,
+		},
+	)
+}
+
+// MarkDeliveryRetry отмечает неудачную попытку, после которой отправка повторится.
+// Время повторной попытки хранится в задаче очереди.
+func (s *PostgresStorage) MarkDeliveryRetry(ctx context.Context, id uint, errorText string) error {
+	return s.updateDelivery(
+		ctx, id, "for retry", map[string]interface{}{
+			"status":   DeliveryStatusPending,
+			"attempts": gorm.Expr("attempts + 1"),
+			"error":    errorText,
+		},
+	)
+}
+
+// MarkDeliveryFailed отмечает неудачную попытку, после которой отправка не повторяется
+func (s *PostgresStorage) MarkDeliveryFailed(ctx context.Context, id uint, errorText string) error {
+	return s.updateDelivery(
+		ctx, id, "failed", map[string]interface{}{
+			"status":   DeliveryStatusFailed,
+			"attempts": gorm.Expr("attempts + 1"),
+			"error":    errorText,
+		},
+	)
+}
+
+// MarkDeliverySkipped отмечает, что отправка больше не нужна
+func (s *PostgresStorage) MarkDeliverySkipped(ctx context.Context, id uint, reason string) error {
+	return s.updateDelivery(
+		ctx, id, "skipped", map[string]interface{}{
+			"status": DeliveryStatusSkipped,
+			"error":  reason,
+		},
+	)
+}
+
+// DeleteDeliveriesBefore удаляет записи журнала рассылки для слотов раньше before
+func (s *PostgresStorage) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("slot < ?", before).Delete(&Delivery{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete deliveries: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDialogNotFound возвращается, если в чате нет активного диалога
+var ErrDialogNotFound = errors.New("dialog not found")
+
+// DialogRepository определяет интерфейс для хранения состояния диалогов
+type DialogRepository interface {
+	GetDialogState(ctx context.Context, chatID int64) (*DialogState, error)
+	SaveDialogState(ctx context.Context, state *DialogState) error
+	DeleteDialogState(ctx context.Context, chatID int64) error
+	DeleteExpiredDialogStates(ctx context.Context, now time.Time) (int64, error)
+}
+
+// GetDialogState получает состояние диалога в чате
+func (s *PostgresStorage) GetDialogState(ctx context.Context, chatID int64) (*DialogState, error) {
+	var state DialogState
+
+	result := s.db.WithContext(ctx).Where("chat_id = ?", chatID).Take(&state)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDialogNotFound
+		}
+		return nil, fmt.Errorf("failed to get dialog state: %w", result.Error)
+	}
+
+	return &state, nil
+}
+
+// SaveDialogState создает или заменяет состояние диалога в чате
+func (s *PostgresStorage) SaveDialogState(ctx context.Context, state *DialogState) error {
+	result := s.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "chat_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"flow", "step", "data", "expires_at", "updated_at"}),
+			},
+		).
+		Create(state)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to save dialog state: %w", result.Error)
+	}
+
+	return nil
+}
+
+// DeleteDialogState завершает диалог в чате
+func (s *PostgresStorage) DeleteDialogState(ctx context.Context, chatID int64) error {
+	result := s.db.WithContext(ctx).Where("chat_id = ?", chatID).Delete(&DialogState{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete dialog state: %w", result.Error)
+	}
+
+	return nil
+}
+
+// DeleteExpiredDialogStates удаляет диалоги, время ожидания ответа в которых истекло
+func (s *PostgresStorage) DeleteExpiredDialogStates(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&DialogState{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired dialog states: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// jobsBatchSize - сколько задач добавляется в очередь одним запросом
+const jobsBatchSize = 500
+
+// ErrJobLeaseLost возвращается, если результат попытки не сохранен: аренда задачи истекла, и задачу
+// уже забрала другая реплика или другой обработчик
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+// JobRepository определяет интерфейс для работы с очередью задач
+type JobRepository interface {
+	EnqueueJobs(ctx context.Context, jobs []Job) error
+	ClaimDueJobs(ctx context.Context, types []string, now time.Time, limit int, lease time.Duration) ([]Job, error)
+	ClaimRateLimitedJobs(
+		ctx context.Context,
+		rateLimit RateLimit,
+		types []string,
+		now time.Time,
+		limit int,
+		lease time.Duration,
+	) ([]Job, error)
+	CompleteJob(ctx context.Context, id uint, attempt int) error
+	RetryJob(ctx context.Context, id uint, attempt int, errorText string, runAt time.Time) error
+	MarkJobDead(ctx context.Context, id uint, attempt int, errorText string) error
+	DeleteJobsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EnqueueJobs добавляет задачи в очередь. Задачи с ключом, который уже есть в очереди, пропускаются.
+func (s *PostgresStorage) EnqueueJobs(ctx context.Context, jobs []Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	result := s.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "key"}},
+				DoNothing: true,
+			},
+		).
+		CreateInBatches(jobs, jobsBatchSize)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to enqueue jobs: %w", result.Error)
+	}
+
+	return nil
+}
+
+// RateLimit - параметры общего лимита скорости задач JobRateLimit
+type RateLimit struct {
+	// Name - название лимита
+	Name string
+	// Rate - сколько задач в секунду можно забрать на всех репликах вместе
+	Rate float64
+	// Burst - сколько задач можно забрать подряд после простоя
+	Burst int
+}
+
+// ClaimDueJobs забирает до limit задач типов types, время выполнения которых наступило к now,
+// и начинает их попытку. Следующее выполнение забранных задач откладывается на lease, поэтому
+// другие реплики их не получат, а если реплика не сохранит результат, задача повторится после lease.
+// Задачи, которые в это время забирает другая реплика, пропускаются (SKIP LOCKED).
+func (s *PostgresStorage) ClaimDueJobs(
+	ctx context.Context,
+	types []string,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]Job, error) {
+	var jobs []Job
+
+	err := s.db.WithContext(ctx).Transaction(
+		func(tx *gorm.DB) error {
+			var err error
+			jobs, err = claimJobs(tx, types, now, limit, lease)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClaimRateLimitedJobs забирает задачи, как ClaimDueJobs, но не больше, чем позволяет общий
+// для всех реплик лимит rateLimit. Строка лимита блокируется до конца транзакции, поэтому
+// реплики расходуют токены по очереди, а токены списываются только за забранные задачи.
+func (s *PostgresStorage) ClaimRateLimitedJobs(
+	ctx context.Context,
+	rateLimit RateLimit,
+	types []string,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]Job, error) {
+	var jobs []Job
+
+	err := s.db.WithContext(ctx).Transaction(
+		func(tx *gorm.DB) error {
+			bucket := JobRateLimit{Name: rateLimit.Name, Tokens: float64(rateLimit.Burst), RefilledAt: now}
+
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("name = ?", rateLimit.Name).
+				Take(&bucket).Error
+			if err != nil {
+				return err
+			}
+
+			// Часы реплик могут расходиться, поэтому время начисления не сдвигается назад
+			if elapsed := now.Sub(bucket.RefilledAt); elapsed > 0 {
+				bucket.Tokens = min(float64(rateLimit.Burst), bucket.Tokens+elapsed.Seconds()*rateLimit.Rate)
+				bucket.RefilledAt = now
+			}
+
+			available := min(limit, int(bucket.Tokens))
+			if available <= 0 {
+				return nil
+			}
+
+			jobs, err = claimJobs(tx, types, now, available, lease)
+			if err != nil || len(jobs) == 0 {
+				return err
+			}
+
+			return tx.Model(&bucket).
+				Updates(
+					map[string]interface{}{
+						"tokens":      bucket.Tokens - float64(len(jobs)),
+						"refilled_at": bucket.RefilledAt,
+					},
+				).Error
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim rate limited jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// claimJobs забирает задачи для ClaimDueJobs и ClaimRateLimitedJobs в транзакции tx
+func claimJobs(tx *gorm.DB, types []string, now time.Time, limit int, lease time.Duration) ([]Job, error) {
+	var jobs []Job
+
+	result := tx.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND run_at <= ?", JobStatusPending, now).
+		Where("type IN ?", types).
+		Order("run_at, id").
+		Limit(limit).
+		Find(&jobs)
+	if result.Error != nil || len(jobs) == 0 {
+		return nil, result.Error
+	}
+
+	ids := make([]uint, 0, len(jobs))
+	for i := range jobs {
+		ids = append(ids, jobs[i].ID)
+		jobs[i].Attempts++
+	}
+
+	err := tx.Model(&Job{}).
+		Where("id IN ?", ids).
+		Updates(
+			map[string]interface{}{
+				"run_at":   now.Add(lease),
+				"attempts": gorm.Expr("attempts + 1"),
+			},
+		).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// updateJob сохраняет результат попытки attempt задачи. Номер попытки служит токеном аренды:
+// если задачу после истечения аренды забрали снова, результат старой попытки не сохраняется
+// и возвращается ErrJobLeaseLost. what используется в тексте ошибки.
+func (s *PostgresStorage) updateJob(
+	ctx context.Context,
+	id uint,
+	attempt int,
+	what string,
+	fields map[string]interface{},
+) error {
+	result := s.db.WithContext(ctx).
+		Model(&Job{}).
+		Where("id = ? AND attempts = ? AND status = ?", id, attempt, JobStatusPending).
+		Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark job %s: %w", what, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+// CompleteJob отмечает попытку attempt успешной, а задачу - выполненной
+func (s *PostgresStorage) CompleteJob(ctx context.Context, id uint, attempt int) error {
+	return s.updateJob(ctx, id, attempt, "done", map[string]interface{}{"status": JobStatusDone, "last_error": ""})
+}
+
+// RetryJob отмечает неудачную попытку attempt, после которой задача повторится в runAt
+func (s *PostgresStorage) RetryJob(ctx context.Context, id uint, attempt int, errorText string, runAt time.Time) error {
+	return s.updateJob(
+		ctx,
+		id,
+		attempt,
+		"for retry",
+		map[string]interface{}{"last_error": errorText, "run_at": runAt},
+	)
+}
+
+// MarkJobDead отмечает неудачную попытку attempt, после которой задача больше не повторяется
+func (s *PostgresStorage) MarkJobDead(ctx context.Context, id uint, attempt int, errorText string) error {
+	return s.updateJob(
+		ctx,
+		id,
+		attempt,
+		"dead",
+		map[string]interface{}{"status": JobStatusDead, "last_error": errorText},
+	)
+}
+
+// DeleteJobsBefore удаляет выполненные задачи и задачи в dead letter, обновленные раньше before
+func (s *PostgresStorage) DeleteJobsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", []string{JobStatusDone, JobStatusDead}, before).
+		Delete(&Job{})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete jobs: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
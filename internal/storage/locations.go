@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrUserLocationNotFound возвращается, если у пользователя нет места с таким ID
+var ErrUserLocationNotFound = errors.New("user location not found")
+
+// LocationRepository определяет интерфейс для работы с сохраненными местами пользователей
+type LocationRepository interface {
+	GetUserLocations(ctx context.Context, chatID int64) ([]UserLocation, error)
+	GetUserLocation(ctx context.Context, chatID int64, id uint) (*UserLocation, error)
+	AddUserLocation(ctx context.Context, location *UserLocation) error
+	DeleteUserLocation(ctx context.Context, chatID int64, id uint) error
+	UpdateUserLocationInMorning(ctx context.Context, chatID int64, id uint, inMorning bool) error
+}
+
+// GetUserLocations получает сохраненные места пользователя в порядке добавления
+func (s *PostgresStorage) GetUserLocations(ctx context.Context, chatID int64) ([]UserLocation, error) {
+	var locations []UserLocation
+
+	result := s.db.WithContext(ctx).Where("chat_id = ?", chatID).Order("id").Find(&locations)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get user locations: %w", result.Error)
+	}
+
+	return locations, nil
+}
+
+// GetUserLocation получает сохраненное место пользователя по ID
+func (s *PostgresStorage) GetUserLocation(ctx context.Context, chatID int64, id uint) (*UserLocation, error) {
+	var location UserLocation
+
+	result := s.db.WithContext(ctx).Where("chat_id = ? AND id = ?", chatID, id).Take(&location)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrUserLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get user location: %w", result.Error)
+	}
+
+	return &location, nil
+}
+
+// AddUserLocation сохраняет новое место пользователя
+func (s *PostgresStorage) AddUserLocation(ctx context.Context, location *UserLocation) error {
+	if err := s.db.WithContext(ctx).Create(location).Error; err != nil {
+		return fmt.Errorf("failed to add user location: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUserLocation удаляет сохраненное место пользователя
+func (s *PostgresStorage) DeleteUserLocation(ctx context.Context, chatID int64, id uint) error {
+	result := s.db.WithContext(ctx).Where("chat_id = ? AND id = ?", chatID, id).Delete(&UserLocation{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete user location: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserLocationNotFound
+	}
+
+	return nil
+}
+
+// UpdateUserLocationInMorning включает или выключает место в утреннем сообщении
+func (s *PostgresStorage) UpdateUserLocationInMorning(ctx context.Context, chatID int64, id uint, inMorning bool) error {
+	result := s.db.WithContext(ctx).
+		Model(&UserLocation{}).
+		Where("chat_id = ? AND id = ?", chatID, id).
+		Update("in_morning", inMorning)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user location: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserLocationNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// LockRepository определяет интерфейс для advisory-блокировок Postgres
+type LockRepository interface {
+	TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error)
+}
+
+// AdvisoryLock - сессионная advisory-блокировка Postgres. Блокировка принадлежит сессии,
+// поэтому удерживается на отдельном соединении, пока ее не снимут или соединение не оборвется.
+type AdvisoryLock struct {
+	key  int64
+	conn *sql.Conn
+}
+
+// TryAdvisoryLock пытается взять блокировку key без ожидания.
+// Возвращает nil без ошибки, если блокировку держит другая сессия.
+func (s *PostgresStorage) TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	if !locked {
+		_ = conn.Close()
+		return nil, nil
+	}
+
+	return &AdvisoryLock{key: key, conn: conn}, nil
+}
+
+// Check проверяет, что соединение с блокировкой живо и блокировка по-прежнему удерживается
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("advisory lock connection lost: %w", err)
+	}
+
+	return nil
+}
+
+// Unlock снимает блокировку и освобождает соединение
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if err != nil {
+		// Соединение закрывается, а не возвращается в пул: с концом сессии блокировка снимается сама
+		_ = l.conn.Raw(
+			func(interface{}) error {
+				return driver.ErrBadConn
+			},
+		)
+	}
+
+	if closeErr := l.conn.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+
+	return nil
+}
@@ -1,13 +1,256 @@
 package storage
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // User представляет пользователя бота
 type User struct {
 	gorm.Model
 	// ChatID - уникальный идентификатор чата в Telegram
 	ChatID int64 `gorm:"uniqueIndex;not null"`
+	// Active - пользователь получает рассылки: не отписался командой /stop и не заблокировал бота
+	Active bool `gorm:"default:true;not null"`
+	// InactiveReason - почему пользователь стал неактивным (значения InactiveReason*)
+	InactiveReason string `gorm:"default:'';not null"`
+	// InactiveSince - когда пользователь стал неактивным
+	InactiveSince *time.Time
 	// WeatherEnabled - флаг включения утренней рассылки погоды
 	WeatherEnabled bool `gorm:"default:true;not null"`
+	// PausedUntil - время, до которого утренняя рассылка приостановлена, nil - не приостановлена
+	PausedUntil *time.Time
+	// DigestEnabled - флаг подписки на еженедельную и ежемесячную сводку погоды
+	DigestEnabled bool `gorm:"default:false;not null"`
+	// ChartEnabled - флаг прикрепления графика прогноза к утренней рассылке
+	ChartEnabled bool `gorm:"default:false;not null"`
+	// AstroEnabled - флаг раздела с восходом, закатом и фазой Луны из версий до Sections.
+	// Учитывается, только пока пользователь не настроил разделы.
+	AstroEnabled bool `gorm:"default:false;not null"`
+	// Sections - включенные разделы утреннего сообщения через запятую в порядке вывода,
+	// пустое - разделы по умолчанию
+	Sections string `gorm:"default:'';not null"`
+	// CommuteEnabled - флаг прогноза на дорогу в утреннем сообщении
+	CommuteEnabled bool `gorm:"default:false;not null"`
+	// CommuteHome, CommuteWork - места дороги: ID сохраненного места (UserLocation) или 0 - основной город
+	CommuteHome uint `gorm:"default:0;not null"`
+	CommuteWork uint `gorm:"default:0;not null"`
+	// CommuteDeparture, CommuteReturn - время выезда из дома и возвращения с работы в минутах от начала суток
+	CommuteDeparture int `gorm:"default:510;not null"`
+	CommuteReturn    int `gorm:"default:1140;not null"`
+	// LocationName - название сохраненного города, пустое - город по умолчанию
+	LocationName string
+	Latitude     float64
+	Longitude    float64
+	// DeliveryTime - время утренней рассылки в минутах от начала суток, nil - время по умолчанию
+	DeliveryTime *int
+	// DeliveryDays - дни недели рассылки, бит i соответствует time.Weekday(i)
+	DeliveryDays int `gorm:"default:127;not null"`
+	// AlertRules - включенные правила предупреждений (битовая маска)
+	AlertRules int `gorm:"default:15;not null"`
+	// LanguageCode - language_code пользователя в Telegram
+	LanguageCode string
+	// Language - язык сообщений, выбранный в настройках; пустой - язык Telegram
+	Language string
+	// TemperatureUnit, SpeedUnit, PressureUnit - единицы измерения в сообщениях (значения units)
+	TemperatureUnit string `gorm:"default:'c';not null"`
+	SpeedUnit       string `gorm:"default:'ms';not null"`
+	PressureUnit    string `gorm:"default:'hpa';not null"`
+}
+
+// Причины, по которым пользователь перестает получать рассылки
+const (
+	// InactiveReasonStopped - пользователь отписался командой /stop
+	InactiveReasonStopped = "stopped"
+	// InactiveReasonBlocked - пользователь заблокировал бота
+	InactiveReasonBlocked = "blocked"
+	// InactiveReasonChatNotFound - чат не найден (удален или бот исключен)
+	InactiveReasonChatNotFound = "chat_not_found"
+	// InactiveReasonDeactivated - аккаунт пользователя удален
+	InactiveReasonDeactivated = "deactivated"
+)
+
+const (
+	// AllDeliveryDays - маска рассылки по всем дням недели
+	AllDeliveryDays = 1<<7 - 1
+	// AllAlertRules - маска всех правил предупреждений
+	AllAlertRules = 1<<4 - 1
+)
+
+// WeatherObservation представляет снимок погоды, полученный от провайдера
+type WeatherObservation struct {
+	ID uint `gorm:"primarykey"`
+	// Location - ключ локации, для которой получен снимок: координаты, округленные до сотых (например, "55.75,37.62")
+	Location string `gorm:"index:idx_observations_location_time,priority:1;not null"`
+	// Provider - источник данных (например, "openweather")
+	Provider string `gorm:"not null"`
+	// ObservedAt - время получения снимка
+	ObservedAt time.Time `gorm:"index:idx_observations_location_time,priority:2;index;not null"`
+
+	Latitude    float64
+	Longitude   float64
+	Temperature float64
+	FeelsLike   float64
+	Description string
+	Humidity    int
+	WindSpeed   float64
+	// Pressure - атмосферное давление, гПа
+	Pressure float64 `gorm:"default:0;not null"`
+	// Condition - погодные условия, значение condition.Condition
+	Condition string `gorm:"default:'';not null"`
+	// Night - снимок получен ночью (по значку провайдера)
+	Night bool `gorm:"default:false;not null"`
+	// Rain, Snow - шел ли дождь или снег
+	Rain bool
+	Snow bool
+	// RainAmount, SnowAmount - количество дождя и снега за последний час, мм
+	RainAmount float64 `gorm:"default:0;not null"`
+	SnowAmount float64 `gorm:"default:0;not null"`
+
+	CreatedAt time.Time
+}
+
+// ObservationExtremes содержит экстремальные значения наблюдений за период
+type ObservationExtremes struct {
+	Count          int64
+	MinTemperature float64
+	MaxTemperature float64
+	MaxWindSpeed   float64
 }
 
+// DialogState хранит состояние многошагового диалога с пользователем
+type DialogState struct {
+	// ChatID - чат, в котором идет диалог; в каждом чате активен не более одного диалога
+	ChatID int64 `gorm:"primaryKey;autoIncrement:false"`
+	// Flow - название сценария диалога
+	Flow string `gorm:"not null"`
+	// Step - текущий шаг сценария
+	Step string `gorm:"not null"`
+	// Data - данные, собранные на предыдущих шагах, в формате JSON
+	Data string `gorm:"not null;default:'{}'"`
+	// ExpiresAt - время, после которого диалог считается прерванным
+	ExpiresAt time.Time `gorm:"index;not null"`
+	UpdatedAt time.Time
+}
+
+// UserLocation - дополнительное сохраненное место пользователя (дом, офис, дача)
+type UserLocation struct {
+	ID uint `gorm:"primarykey"`
+	// ChatID - пользователь, которому принадлежит место
+	ChatID int64 `gorm:"index;not null"`
+	// Name - название места, заданное пользователем
+	Name      string  `gorm:"not null"`
+	Latitude  float64 `gorm:"not null"`
+	Longitude float64 `gorm:"not null"`
+	// InMorning - место входит в утреннее сообщение
+	InMorning bool `gorm:"default:true;not null"`
+	CreatedAt time.Time
+}
+
+// Trip - поездка пользователя: на даты поездки утренний прогноз приходит для места поездки.
+// У пользователя не больше одной поездки; настройки дома при этом не меняются.
+type Trip struct {
+	ID uint `gorm:"primarykey"`
+	// ChatID - пользователь, которому принадлежит поездка
+	ChatID int64 `gorm:"uniqueIndex;not null"`
+	// Name - название места поездки
+	Name      string  `gorm:"not null"`
+	Latitude  float64 `gorm:"not null"`
+	Longitude float64 `gorm:"not null"`
+	// UTCOffset - текущее смещение часового пояса места поездки от UTC в секундах. Провайдер сообщает
+	// только текущее смещение, поэтому во время поездки оно обновляется при переходе на летнее или зимнее время.
+	UTCOffset int `gorm:"not null"`
+	// StartDate, EndDate - первый и последний день поездки (по местному времени места поездки)
+	StartDate time.Time `gorm:"type:date;index;not null"`
+	EndDate   time.Time `gorm:"type:date;index;not null"`
+	// PackingSent - прогноз для сборов уже отправлен
+	PackingSent bool `gorm:"default:false;not null"`
+	CreatedAt   time.Time
+}
+
+// Delivery - запись журнала утренней рассылки: прогноз, который нужно отправить пользователю в слот.
+// Журнал защищает от повторной отправки после перезапуска и позволяет повторить неудачную отправку.
+type Delivery struct {
+	ID uint `gorm:"primarykey"`
+	// ChatID - получатель; для пользователя и слота есть не больше одной записи
+	ChatID int64 `gorm:"uniqueIndex:idx_deliveries_chat_slot,priority:1;not null"`
+	// Slot - минута рассылки по времени бота
+	Slot time.Time `gorm:"uniqueIndex:idx_deliveries_chat_slot,priority:2;index;not null"`
+	// TripID - поездка, для места которой отправляется прогноз, 0 - прогноз для дома
+	TripID uint `gorm:"default:0;not null"`
+	// Status - состояние отправки (значения DeliveryStatus*)
+	Status string `gorm:"index;not null"`
+	// Attempts - количество попыток отправки
+	Attempts int `gorm:"default:0;not null"`
+	// Error - ошибка последней попытки или причина пропуска
+	Error string `gorm:"default:'';not null"`
+	// SentParts - сколько сообщений утреннего прогноза уже отправлено: прогноз для нескольких мест
+	// может занимать несколько сообщений, и повторная попытка продолжает с первого неотправленного
+	SentParts int `gorm:"default:0;not null"`
+	// Messages - сообщения прогноза в формате JSON, собранные перед первой отправкой: повторная
+	// попытка отправляет те же сообщения, поэтому их границы не сдвигаются
+	Messages string `gorm:"type:text;default:'';not null"`
+	// MessageID - ID последнего отправленного сообщения в Telegram
+	MessageID int `gorm:"default:0;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Состояния записи журнала рассылки
+const (
+	// DeliveryStatusPending - прогноз еще не отправлен, отправка ожидается или будет повторена
+	DeliveryStatusPending = "pending"
+	// DeliveryStatusSending - прогноз отправляется: запись забрана обработчиком
+	DeliveryStatusSending = "sending"
+	// DeliveryStatusSent - прогноз отправлен
+	DeliveryStatusSent = "sent"
+	// DeliveryStatusFailed - прогноз не отправлен: пользователь недоступен или попытки закончились
+	DeliveryStatusFailed = "failed"
+	// DeliveryStatusSkipped - отправка не нужна: к моменту отправки пользователь отключил рассылку
+	DeliveryStatusSkipped = "skipped"
+)
+
+// Job - задача очереди, которую нужно выполнить в RunAt
+type Job struct {
+	ID uint `gorm:"primarykey"`
+	// Type - тип задачи, по которому выбирается обработчик
+	Type string `gorm:"index;not null"`
+	// Key - ключ идемпотентности: задача с ключом, который уже есть в очереди, не добавляется; nil - без ключа
+	Key *string `gorm:"uniqueIndex"`
+	// Payload - параметры задачи в формате JSON
+	Payload string `gorm:"not null;default:'{}'"`
+	// Status - состояние задачи (значения JobStatus*)
+	Status string `gorm:"index:idx_jobs_due,priority:1;not null"`
+	// RunAt - время, не раньше которого задача выполняется
+	RunAt time.Time `gorm:"index:idx_jobs_due,priority:2;not null"`
+	// Attempts - количество начатых попыток выполнения
+	Attempts int `gorm:"default:0;not null"`
+	// MaxAttempts - после скольких неудачных попыток задача отправляется в dead letter, 0 - без ограничения
+	MaxAttempts int `gorm:"default:0;not null"`
+	// LastError - ошибка последней неудачной попытки
+	LastError string `gorm:"default:'';not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Состояния задачи очереди
+const (
+	// JobStatusPending - задача ждет выполнения или повторной попытки
+	JobStatusPending = "pending"
+	// JobStatusDone - задача выполнена
+	JobStatusDone = "done"
+	// JobStatusDead - задача не выполнена и больше не повторяется (dead letter)
+	JobStatusDead = "dead"
+)
+
+// JobRateLimit - общий для всех реплик лимит скорости выполнения задач по алгоритму "token bucket":
+// каждая забранная задача расходует токен, токены начисляются с заданной скоростью
+type JobRateLimit struct {
+	// Name - название лимита; один лимит может ограничивать несколько типов задач
+	Name string `gorm:"primaryKey"`
+	// Tokens - сколько задач можно забрать на момент RefilledAt
+	Tokens float64 `gorm:"not null"`
+	// RefilledAt - когда токены начислялись последний раз
+	RefilledAt time.Time `gorm:"not null"`
+}
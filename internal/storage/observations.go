@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrObservationNotFound возвращается, если подходящее наблюдение не найдено
+var ErrObservationNotFound = errors.New("observation not found")
+
+// ObservationRepository определяет интерфейс для работы с историей наблюдений погоды
+type ObservationRepository interface {
+	SaveObservation(ctx context.Context, observation *WeatherObservation) error
+	GetLatestObservation(ctx context.Context, location string) (*WeatherObservation, error)
+	GetNearestObservation(ctx context.Context, location string, at time.Time, tolerance time.Duration) (
+		*WeatherObservation,
+		error,
+	)
+	GetObservations(ctx context.Context, location string, from, to time.Time) ([]*WeatherObservation, error)
+	GetObservationExtremes(ctx context.Context, location string, from, to time.Time) (*ObservationExtremes, error)
+	DeleteObservationsBefore(ctx context.Context, before time.Time) (int64, error)
+	GetLocationsInUse(ctx context.Context) ([]Coordinates, error)
+	RekeyObservations(ctx context.Context, key func(latitude, longitude float64) string) (int64, error)
+}
+
+// Coordinates - координаты места
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// SaveObservation сохраняет снимок погоды
+func (s *PostgresStorage) SaveObservation(ctx context.Context, observation *WeatherObservation) error {
+	if err := s.db.WithContext(ctx).Create(observation).Error; err != nil {
+		return fmt.Errorf("failed to save observation: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestObservation получает последнее наблюдение для локации
+func (s *PostgresStorage) GetLatestObservation(ctx context.Context, location string) (*WeatherObservation, error) {
+	var observation WeatherObservation
+
+	result := s.db.WithContext(ctx).
+		Where("location = ?", location).
+		Order("observed_at DESC").
+		Take(&observation)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrObservationNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest observation: %w", result.Error)
+	}
+
+	return &observation, nil
+}
+
+// GetNearestObservation получает наблюдение для локации, ближайшее к указанному времени
+// в пределах допуска tolerance
+func (s *PostgresStorage) GetNearestObservation(
+	ctx context.Context,
+	location string,
+	at time.Time,
+	tolerance time.Duration,
+) (*WeatherObservation, error) {
+	var observation WeatherObservation
+
+	result := s.db.WithContext(ctx).
+		Where("location = ?", location).
+		Where("observed_at BETWEEN ? AND ?", at.Add(-tolerance), at.Add(tolerance)).
+		Clauses(
+			clause.OrderBy{
+				Expression: clause.Expr{
+					SQL:                "ABS(EXTRACT(EPOCH FROM (observed_at - ?)))",
+					Vars:               []interface{}{at},
+					WithoutParentheses: true,
+				},
+			},
+		).
+		Take(&observation)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrObservationNotFound
+		}
+		return nil, fmt.Errorf("failed to get nearest observation: %w", result.Error)
+	}
+
+	return &observation, nil
+}
+
+// GetObservations получает наблюдения для локации в интервале [from, to), упорядоченные по времени
+func (s *PostgresStorage) GetObservations(ctx context.Context, location string, from, to time.Time) (
+	[]*WeatherObservation,
+	error,
+) {
+	var observations []*WeatherObservation
+
+	result := s.db.WithContext(ctx).
+		Where("location = ?", location).
+		Where("observed_at >= ? AND observed_at < ?", from, to).
+		Order("observed_at").
+		Find(&observations)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get observations: %w", result.Error)
+	}
+
+	return observations, nil
+}
+
+// GetObservationExtremes получает экстремальные значения наблюдений для локации в интервале [from, to)
+func (s *PostgresStorage) GetObservationExtremes(ctx context.Context, location string, from, to time.Time) (
+	*ObservationExtremes,
+	error,
+) {
+	var extremes ObservationExtremes
+
+	result := s.db.WithContext(ctx).
+		Model(&WeatherObservation{}).
+		Select(
+			"COUNT(*) AS count, "+
+				"COALESCE(MIN(temperature), 0) AS min_temperature, "+
+				"COALESCE(MAX(temperature), 0) AS max_temperature, "+
+				"COALESCE(MAX(wind_speed), 0) AS max_wind_speed",
+		).
+		Where("location = ?", location).
+		Where("observed_at >= ? AND observed_at < ?", from, to).
+		Scan(&extremes)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get observation extremes: %w", result.Error)
+	}
+
+	return &extremes, nil
+}
+
+// DeleteObservationsBefore удаляет снимки погоды, полученные раньше указанного времени
+func (s *PostgresStorage) DeleteObservationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("observed_at < ?", before).
+		Delete(&WeatherObservation{})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete observations: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// GetLocationsInUse получает координаты мест, погоду в которых смотрят пользователи: основные города
+// и сохраненные места активных пользователей и места поездок. Город по умолчанию не входит в список.
+func (s *PostgresStorage) GetLocationsInUse(ctx context.Context) ([]Coordinates, error) {
+	var locations []Coordinates
+
+	result := s.db.WithContext(ctx).Raw(
+		`SELECT latitude, longitude FROM users
+		WHERE active AND deleted_at IS NULL AND location_name <> ''
+		UNION
+		SELECT l.latitude, l.longitude FROM user_locations l
+		JOIN users u ON u.chat_id = l.chat_id
+		WHERE u.active AND u.deleted_at IS NULL
+		UNION
+		SELECT latitude, longitude FROM trips`,
+	).Scan(&locations)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get locations in use: %w", result.Error)
+	}
+
+	return locations, nil
+}
+
+// RekeyObservations переносит наблюдения, сохраненные под ключом из названия места (например, "Moscow,RU"),
+// на ключ, который key возвращает для координат наблюдения. Возвращает количество перенесенных наблюдений.
+func (s *PostgresStorage) RekeyObservations(
+	ctx context.Context,
+	key func(latitude, longitude float64) string,
+) (int64, error) {
+	var locations []struct {
+		Location  string
+		Latitude  float64
+		Longitude float64
+	}
+
+	// Ключ из координат состоит только из цифр, точек, запятой и минусов
+	result := s.db.WithContext(ctx).
+		Model(&WeatherObservation{}).
+		Distinct("location", "latitude", "longitude").
+		Where("location ~ ?", "[^0-9.,-]").
+		Scan(&locations)
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to get observation locations: %w", result.Error)
+	}
+
+	var updated int64
+	for _, l := range locations {
+		result := s.db.WithContext(ctx).
+			Model(&WeatherObservation{}).
+			Where("location = ? AND latitude = ? AND longitude = ?", l.Location, l.Latitude, l.Longitude).
+			Update("location", key(l.Latitude, l.Longitude))
+
+		if result.Error != nil {
+			return updated, fmt.Errorf("failed to rekey observations of %s: %w", l.Location, result.Error)
+		}
+
+		updated += result.RowsAffected
+	}
+
+	return updated, nil
+}
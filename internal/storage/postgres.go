@@ -15,11 +15,39 @@ import (
 type UserRepository interface {
 	CreateUser(ctx context.Context, chatID int64) error
 	GetUser(ctx context.Context, chatID int64) (*User, error)
+	GetUsers(ctx context.Context, chatIDs []int64) ([]*User, error)
 	UpdateWeatherEnabled(ctx context.Context, chatID int64, enabled bool) error
+	DeactivateUser(ctx context.Context, chatID int64, reason string) error
+	ActivateUser(ctx context.Context, chatID int64) error
+	UpdatePausedUntil(ctx context.Context, chatID int64, pausedUntil *time.Time) error
 	GetAllEnabledUsers(ctx context.Context) ([]*User, error)
+	UpdateDigestEnabled(ctx context.Context, chatID int64, enabled bool) error
+	GetAllDigestUsers(ctx context.Context) ([]*User, error)
+	UpdateChartEnabled(ctx context.Context, chatID int64, enabled bool) error
+	UpdateSections(ctx context.Context, chatID int64, sections string) error
+	UpdateCommuteEnabled(ctx context.Context, chatID int64, enabled bool) error
+	UpdateCommuteLocations(ctx context.Context, chatID int64, home, work uint) error
+	UpdateCommuteTimes(ctx context.Context, chatID int64, departure, ret int) error
+	UpdateLocation(ctx context.Context, chatID int64, name string, latitude, longitude float64) error
+	UpdateDeliveryTime(ctx context.Context, chatID int64, minute *int) error
+	UpdateDeliveryDays(ctx context.Context, chatID int64, days int) error
+	UpdateAlertRules(ctx context.Context, chatID int64, rules int) error
+	GetUsersForDelivery(
+		ctx context.Context,
+		minute int,
+		isDefault bool,
+		weekday time.Weekday,
+		now time.Time,
+	) ([]*User, error)
+	UpdateLanguageCode(ctx context.Context, chatID int64, code string) error
+	UpdateLanguage(ctx context.Context, chatID int64, language string) error
+	UpdateUnits(ctx context.Context, chatID int64, temperature, speed, pressure string) error
 }
 
-// PostgresStorage реализует UserRepository для PostgreSQL
+// migrationLockKey - ключ advisory-блокировки на время миграций
+const migrationLockKey int64 = 0x44434201
+
+// PostgresStorage реализует репозитории для PostgreSQL
 type PostgresStorage struct {
 	db *gorm.DB
 }
@@ -56,8 +84,25 @@ func NewPostgresStorage(ctx context.Context, databaseURL string) (*PostgresStora
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	// AutoMigrate для создания таблиц
-	if err := db.AutoMigrate(&User{}); err != nil {
+	// AutoMigrate для создания таблиц. Реплики, запущенные одновременно, выполняют миграции по очереди.
+	err = db.WithContext(ctx).Transaction(
+		func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+
+			if err := tx.AutoMigrate(
+				&User{}, &WeatherObservation{}, &DialogState{}, &UserLocation{}, &Trip{}, &Delivery{}, &Job{},
+				&JobRateLimit{},
+			); err != nil {
+				return err
+			}
+
+			// Время повторной отправки прогноза хранится в задаче очереди
+			return tx.Exec("ALTER TABLE deliveries DROP COLUMN IF EXISTS next_attempt_at").Error
+		},
+	)
+	if err != nil {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
@@ -79,7 +124,10 @@ func (s *PostgresStorage) Close() error {
 func (s *PostgresStorage) CreateUser(ctx context.Context, chatID int64) error {
 	user := &User{
 		ChatID:         chatID,
+		Active:         true,
 		WeatherEnabled: true,
+		DeliveryDays:   AllDeliveryDays,
+		AlertRules:     AllAlertRules,
 	}
 
 	result := s.db.WithContext(ctx).Where("chat_id = ?", chatID).FirstOrCreate(user)
@@ -105,15 +153,31 @@ func (s *PostgresStorage) GetUser(ctx context.Context, chatID int64) (*User, err
 	return &user, nil
 }
 
-// UpdateWeatherEnabled обновляет статус рассылки погоды для пользователя
-func (s *PostgresStorage) UpdateWeatherEnabled(ctx context.Context, chatID int64, enabled bool) error {
+// GetUsers получает пользователей по chatIDs одним запросом; несуществующие пропускаются
+func (s *PostgresStorage) GetUsers(ctx context.Context, chatIDs []int64) ([]*User, error) {
+	if len(chatIDs) == 0 {
+		return nil, nil
+	}
+
+	var users []*User
+
+	result := s.db.WithContext(ctx).Where("chat_id IN ?", chatIDs).Find(&users)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get users: %w", result.Error)
+	}
+
+	return users, nil
+}
+
+// updateUser обновляет поля пользователя; what описывает изменение для текста ошибки
+func (s *PostgresStorage) updateUser(ctx context.Context, chatID int64, what string, fields map[string]interface{}) error {
 	result := s.db.WithContext(ctx).
 		Model(&User{}).
 		Where("chat_id = ?", chatID).
-		Update("weather_enabled", enabled)
+		Updates(fields)
 
 	if result.Error != nil {
-		return fmt.Errorf("failed to update weather enabled: %w", result.Error)
+		return fmt.Errorf("failed to update %s: %w", what, result.Error)
 	}
 
 	if result.RowsAffected == 0 {
@@ -123,11 +187,43 @@ func (s *PostgresStorage) UpdateWeatherEnabled(ctx context.Context, chatID int64
 	return nil
 }
 
-// GetAllEnabledUsers получает всех пользователей с включенной рассылкой погоды
+// UpdateWeatherEnabled обновляет статус рассылки погоды для пользователя
+func (s *PostgresStorage) UpdateWeatherEnabled(ctx context.Context, chatID int64, enabled bool) error {
+	return s.updateUser(ctx, chatID, "weather enabled", map[string]interface{}{"weather_enabled": enabled})
+}
+
+// DeactivateUser отключает пользователю все рассылки по причине reason (InactiveReason*)
+func (s *PostgresStorage) DeactivateUser(ctx context.Context, chatID int64, reason string) error {
+	return s.updateUser(
+		ctx, chatID, "user activity", map[string]interface{}{
+			"active":          false,
+			"inactive_reason": reason,
+			"inactive_since":  time.Now().UTC(),
+		},
+	)
+}
+
+// ActivateUser возвращает пользователю рассылки с прежними настройками
+func (s *PostgresStorage) ActivateUser(ctx context.Context, chatID int64) error {
+	return s.updateUser(
+		ctx, chatID, "user activity", map[string]interface{}{
+			"active":          true,
+			"inactive_reason": "",
+			"inactive_since":  nil,
+		},
+	)
+}
+
+// UpdatePausedUntil приостанавливает утреннюю рассылку до pausedUntil; nil возобновляет рассылку
+func (s *PostgresStorage) UpdatePausedUntil(ctx context.Context, chatID int64, pausedUntil *time.Time) error {
+	return s.updateUser(ctx, chatID, "paused until", map[string]interface{}{"paused_until": pausedUntil})
+}
+
+// GetAllEnabledUsers получает всех активных пользователей с включенной рассылкой погоды
 func (s *PostgresStorage) GetAllEnabledUsers(ctx context.Context) ([]*User, error) {
 	var users []*User
 
-	result := s.db.WithContext(ctx).Where("weather_enabled = ?", true).Find(&users)
+	result := s.db.WithContext(ctx).Where("active = ? AND weather_enabled = ?", true, true).Find(&users)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to get enabled users: %w", result.Error)
 	}
@@ -135,3 +231,129 @@ func (s *PostgresStorage) GetAllEnabledUsers(ctx context.Context) ([]*User, erro
 	return users, nil
 }
 
+// GetUsersForDelivery получает активных пользователей с включенной рассылкой, которым прогноз
+// нужно отправить в minute (минута от начала суток) дня недели weekday.
+// isDefault означает, что minute совпадает со временем рассылки по умолчанию.
+// Пользователи, приостановившие рассылку позже now, пропускаются.
+func (s *PostgresStorage) GetUsersForDelivery(
+	ctx context.Context,
+	minute int,
+	isDefault bool,
+	weekday time.Weekday,
+	now time.Time,
+) ([]*User, error) {
+	var users []*User
+
+	result := s.db.WithContext(ctx).
+		Where("active = ? AND weather_enabled = ?", true, true).
+		Where("delivery_time = ? OR (delivery_time IS NULL AND ?)", minute, isDefault).
+		Where("delivery_days & ? <> 0", 1<<weekday).
+		Where("paused_until IS NULL OR paused_until <= ?", now).
+		Find(&users)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get users for delivery: %w", result.Error)
+	}
+
+	return users, nil
+}
+
+// UpdateDigestEnabled обновляет подписку пользователя на сводку погоды
+func (s *PostgresStorage) UpdateDigestEnabled(ctx context.Context, chatID int64, enabled bool) error {
+	return s.updateUser(ctx, chatID, "digest enabled", map[string]interface{}{"digest_enabled": enabled})
+}
+
+// UpdateChartEnabled обновляет флаг прикрепления графика к утренней рассылке
+func (s *PostgresStorage) UpdateChartEnabled(ctx context.Context, chatID int64, enabled bool) error {
+	return s.updateUser(ctx, chatID, "chart enabled", map[string]interface{}{"chart_enabled": enabled})
+}
+
+// UpdateSections обновляет список и порядок разделов утреннего сообщения
+func (s *PostgresStorage) UpdateSections(ctx context.Context, chatID int64, sections string) error {
+	return s.updateUser(ctx, chatID, "sections", map[string]interface{}{"sections": sections})
+}
+
+// UpdateCommuteEnabled включает или выключает прогноз на дорогу
+func (s *PostgresStorage) UpdateCommuteEnabled(ctx context.Context, chatID int64, enabled bool) error {
+	return s.updateUser(ctx, chatID, "commute enabled", map[string]interface{}{"commute_enabled": enabled})
+}
+
+// UpdateCommuteLocations обновляет места дороги: дом и работу
+func (s *PostgresStorage) UpdateCommuteLocations(ctx context.Context, chatID int64, home, work uint) error {
+	return s.updateUser(
+		ctx, chatID, "commute locations", map[string]interface{}{
+			"commute_home": home,
+			"commute_work": work,
+		},
+	)
+}
+
+// UpdateCommuteTimes обновляет время выезда и возвращения (минуты от начала суток)
+func (s *PostgresStorage) UpdateCommuteTimes(ctx context.Context, chatID int64, departure, ret int) error {
+	return s.updateUser(
+		ctx, chatID, "commute times", map[string]interface{}{
+			"commute_departure": departure,
+			"commute_return":    ret,
+		},
+	)
+}
+
+// UpdateLocation сохраняет город пользователя; пустое название возвращает город по умолчанию
+func (s *PostgresStorage) UpdateLocation(ctx context.Context, chatID int64, name string, latitude, longitude float64) error {
+	return s.updateUser(
+		ctx, chatID, "location", map[string]interface{}{
+			"location_name": name,
+			"latitude":      latitude,
+			"longitude":     longitude,
+		},
+	)
+}
+
+// UpdateDeliveryTime обновляет время утренней рассылки (минута от начала суток),
+// nil возвращает время по умолчанию
+func (s *PostgresStorage) UpdateDeliveryTime(ctx context.Context, chatID int64, minute *int) error {
+	return s.updateUser(ctx, chatID, "delivery time", map[string]interface{}{"delivery_time": minute})
+}
+
+// UpdateDeliveryDays обновляет дни недели утренней рассылки
+func (s *PostgresStorage) UpdateDeliveryDays(ctx context.Context, chatID int64, days int) error {
+	return s.updateUser(ctx, chatID, "delivery days", map[string]interface{}{"delivery_days": days})
+}
+
+// UpdateAlertRules обновляет включенные правила предупреждений
+func (s *PostgresStorage) UpdateAlertRules(ctx context.Context, chatID int64, rules int) error {
+	return s.updateUser(ctx, chatID, "alert rules", map[string]interface{}{"alert_rules": rules})
+}
+
+// UpdateLanguageCode обновляет language_code пользователя из Telegram
+func (s *PostgresStorage) UpdateLanguageCode(ctx context.Context, chatID int64, code string) error {
+	return s.updateUser(ctx, chatID, "language code", map[string]interface{}{"language_code": code})
+}
+
+// UpdateLanguage обновляет язык сообщений; пустой язык означает язык Telegram
+func (s *PostgresStorage) UpdateLanguage(ctx context.Context, chatID int64, language string) error {
+	return s.updateUser(ctx, chatID, "language", map[string]interface{}{"language": language})
+}
+
+// UpdateUnits обновляет единицы измерения температуры, скорости ветра и давления
+func (s *PostgresStorage) UpdateUnits(ctx context.Context, chatID int64, temperature, speed, pressure string) error {
+	return s.updateUser(
+		ctx, chatID, "units", map[string]interface{}{
+			"temperature_unit": temperature,
+			"speed_unit":       speed,
+			"pressure_unit":    pressure,
+		},
+	)
+}
+
+// GetAllDigestUsers получает всех активных пользователей, подписанных на сводку погоды
+func (s *PostgresStorage) GetAllDigestUsers(ctx context.Context) ([]*User, error) {
+	var users []*User
+
+	result := s.db.WithContext(ctx).Where("active = ? AND digest_enabled = ?", true, true).Find(&users)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get digest users: %w", result.Error)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTripNotFound возвращается, если у пользователя нет поездки
+var ErrTripNotFound = errors.New("trip not found")
+
+// TripRepository определяет интерфейс для работы с поездками пользователей
+type TripRepository interface {
+	GetTrip(ctx context.Context, chatID int64) (*Trip, error)
+	SaveTrip(ctx context.Context, trip *Trip) error
+	DeleteTrip(ctx context.Context, chatID int64) error
+	GetTripsBetween(ctx context.Context, from, to time.Time) ([]Trip, error)
+	GetTripsForPacking(ctx context.Context, from, to time.Time) ([]Trip, error)
+	MarkTripPackingSent(ctx context.Context, id uint) error
+	UpdateTripOffset(ctx context.Context, id uint, offset int) error
+	GetTripsEndedBefore(ctx context.Context, date time.Time) ([]Trip, error)
+}
+
+// GetTrip получает поездку пользователя
+func (s *PostgresStorage) GetTrip(ctx context.Context, chatID int64) (*Trip, error) {
+	var trip Trip
+
+	result := s.db.WithContext(ctx).Where("chat_id = ?", chatID).Take(&trip)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrTripNotFound
+		}
+		return nil, fmt.Errorf("failed to get trip: %w", result.Error)
+	}
+
+	return &trip, nil
+}
+
+// SaveTrip создает или заменяет поездку пользователя
+func (s *PostgresStorage) SaveTrip(ctx context.Context, trip *Trip) error {
+	result := s.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "chat_id"}},
+				DoUpdates: clause.AssignmentColumns(
+					[]string{"name", "latitude", "longitude", "utc_offset", "start_date", "end_date", "packing_sent"},
+				),
+			},
+		).
+		Create(trip)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to save trip: %w", result.Error)
+	}
+
+	return nil
+}
+
+// DeleteTrip удаляет поездку пользователя
+func (s *PostgresStorage) DeleteTrip(ctx context.Context, chatID int64) error {
+	result := s.db.WithContext(ctx).Where("chat_id = ?", chatID).Delete(&Trip{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete trip: %w", result.Error)
+	}
+
+	return nil
+}
+
+// GetTripsBetween получает поездки, которые пересекаются с днями с from по to включительно
+func (s *PostgresStorage) GetTripsBetween(ctx context.Context, from, to time.Time) ([]Trip, error) {
+	var trips []Trip
+
+	result := s.db.WithContext(ctx).
+		Where("start_date <= ? AND end_date >= ?", to, from).
+		Find(&trips)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get trips: %w", result.Error)
+	}
+
+	return trips, nil
+}
+
+// GetTripsForPacking получает поездки, которые начинаются с from по to включительно
+// и для которых прогноз для сборов еще не отправлен
+func (s *PostgresStorage) GetTripsForPacking(ctx context.Context, from, to time.Time) ([]Trip, error) {
+	var trips []Trip
+
+	result := s.db.WithContext(ctx).
+		Where("packing_sent = ?", false).
+		Where("start_date BETWEEN ? AND ?", from, to).
+		Find(&trips)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get trips for packing: %w", result.Error)
+	}
+
+	return trips, nil
+}
+
+// MarkTripPackingSent отмечает, что прогноз для сборов отправлен
+func (s *PostgresStorage) MarkTripPackingSent(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Model(&Trip{}).Where("id = ?", id).Update("packing_sent", true)
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark trip packing sent: %w", result.Error)
+	}
+
+	return nil
+}
+
+// UpdateTripOffset обновляет смещение часового пояса места поездки
+func (s *PostgresStorage) UpdateTripOffset(ctx context.Context, id uint, offset int) error {
+	result := s.db.WithContext(ctx).Model(&Trip{}).Where("id = ?", id).Update("utc_offset", offset)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update trip offset: %w", result.Error)
+	}
+
+	return nil
+}
+
+// GetTripsEndedBefore получает поездки, последний день которых раньше date
+func (s *PostgresStorage) GetTripsEndedBefore(ctx context.Context, date time.Time) ([]Trip, error) {
+	var trips []Trip
+
+	result := s.db.WithContext(ctx).Where("end_date < ?", date).Find(&trips)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get ended trips: %w", result.Error)
+	}
+
+	return trips, nil
+}
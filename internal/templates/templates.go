@@ -0,0 +1,97 @@
+package templates
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// pattern - шаблон имен файлов с шаблонами сообщений
+const pattern = "*.tmpl"
+
+//go:embed *.tmpl
+var embedded embed.FS
+
+// Renderer форматирует сообщения по шаблонам text/template
+type Renderer struct {
+	tmpl *template.Template
+
+	// locales - шаблоны с функциями получателей по ключу locale, см. Render
+	locales   map[string]*template.Template
+	localesMu sync.Mutex
+}
+
+// New загружает встроенные шаблоны, а затем шаблоны из каталога dir, если он задан.
+// Шаблоны из dir переопределяют встроенные блоки с теми же именами ({{define "..."}}),
+// поэтому для изменения одного блока достаточно файла только с ним.
+// funcs задает вспомогательные функции, которые можно заменить при форматировании.
+func New(dir string, funcs template.FuncMap) (*Renderer, error) {
+	tmpl, err := template.New("messages").Funcs(funcs).ParseFS(embedded, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded templates: %w", err)
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates in %s: %w", dir, err)
+		}
+
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template %s: %w", file, err)
+			}
+
+			if _, err := tmpl.New(filepath.Base(file)).Parse(string(content)); err != nil {
+				return nil, fmt.Errorf("failed to parse template %s: %w", file, err)
+			}
+		}
+	}
+
+	return &Renderer{
+		tmpl:    tmpl,
+		locales: make(map[string]*template.Template),
+	}, nil
+}
+
+// Render форматирует блок name с данными data. funcs заменяют вспомогательные функции
+// с теми же именами, например подставляют язык и единицы измерения получателя, и должны
+// зависеть только от ключа locale: шаблоны с ними готовятся один раз для каждого locale.
+func (r *Renderer) Render(name, locale string, data any, funcs template.FuncMap) (string, error) {
+	tmpl, err := r.localized(locale, funcs)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// localized возвращает шаблоны с функциями funcs для ключа locale
+func (r *Renderer) localized(locale string, funcs template.FuncMap) (*template.Template, error) {
+	r.localesMu.Lock()
+	defer r.localesMu.Unlock()
+
+	if tmpl, ok := r.locales[locale]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := r.tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone templates: %w", err)
+	}
+	tmpl.Funcs(funcs)
+
+	r.locales[locale] = tmpl
+
+	return tmpl, nil
+}
@@ -0,0 +1,124 @@
+package units
+
+import "slices"
+
+const (
+	// fahrenheitScale - цена градуса Цельсия в градусах Фаренгейта
+	fahrenheitScale = 9.0 / 5.0
+	// fahrenheitOffset - температура замерзания воды по Фаренгейту
+	fahrenheitOffset = 32.0
+
+	kilometersPerHourInMeterPerSecond = 3.6
+	milesPerHourInMeterPerSecond      = 3600 / 1609.344
+	knotsInMeterPerSecond             = 3600 / 1852.0
+
+	// millimetersOfMercuryInHectopascal - миллиметров ртутного столба в одном гектопаскале
+	millimetersOfMercuryInHectopascal = 0.750061683
+)
+
+// Temperature - единица измерения температуры
+type Temperature string
+
+const (
+	Celsius    Temperature = "c"
+	Fahrenheit Temperature = "f"
+)
+
+// Speed - единица измерения скорости ветра
+type Speed string
+
+const (
+	MetersPerSecond   Speed = "ms"
+	KilometersPerHour Speed = "kmh"
+	MilesPerHour      Speed = "mph"
+	Knots             Speed = "kn"
+)
+
+// Pressure - единица измерения атмосферного давления
+type Pressure string
+
+const (
+	Hectopascal          Pressure = "hpa"
+	MillimetersOfMercury Pressure = "mmhg"
+)
+
+// Доступные единицы в порядке отображения
+var (
+	Temperatures = []Temperature{Celsius, Fahrenheit}
+	Speeds       = []Speed{MetersPerSecond, KilometersPerHour, MilesPerHour, Knots}
+	Pressures    = []Pressure{Hectopascal, MillimetersOfMercury}
+)
+
+// Preferences - единицы измерения, в которых пользователь видит значения
+type Preferences struct {
+	Temperature Temperature
+	Speed       Speed
+	Pressure    Pressure
+}
+
+// Metric возвращает метрические единицы, в которых хранятся данные о погоде
+func Metric() Preferences {
+	return Preferences{
+		Temperature: Celsius,
+		Speed:       MetersPerSecond,
+		Pressure:    Hectopascal,
+	}
+}
+
+// Parse разбирает сохраненные единицы; неизвестные значения заменяются метрическими
+func Parse(temperature, speed, pressure string) Preferences {
+	preferences := Metric()
+
+	if slices.Contains(Temperatures, Temperature(temperature)) {
+		preferences.Temperature = Temperature(temperature)
+	}
+	if slices.Contains(Speeds, Speed(speed)) {
+		preferences.Speed = Speed(speed)
+	}
+	if slices.Contains(Pressures, Pressure(pressure)) {
+		preferences.Pressure = Pressure(pressure)
+	}
+
+	return preferences
+}
+
+// FromCelsius переводит температуру из градусов Цельсия
+func (u Temperature) FromCelsius(celsius float64) float64 {
+	if u == Fahrenheit {
+		return celsius*fahrenheitScale + fahrenheitOffset
+	}
+
+	return celsius
+}
+
+// DeltaFromCelsius переводит разницу температур из градусов Цельсия
+func (u Temperature) DeltaFromCelsius(delta float64) float64 {
+	if u == Fahrenheit {
+		return delta * fahrenheitScale
+	}
+
+	return delta
+}
+
+// FromMetersPerSecond переводит скорость из метров в секунду
+func (u Speed) FromMetersPerSecond(speed float64) float64 {
+	switch u {
+	case KilometersPerHour:
+		return speed * kilometersPerHourInMeterPerSecond
+	case MilesPerHour:
+		return speed * milesPerHourInMeterPerSecond
+	case Knots:
+		return speed * knotsInMeterPerSecond
+	default:
+		return speed
+	}
+}
+
+// FromHectopascals переводит давление из гектопаскалей
+func (u Pressure) FromHectopascals(pressure float64) float64 {
+	if u == MillimetersOfMercury {
+		return pressure * millimetersOfMercuryInHectopascal
+	}
+
+	return pressure
+}
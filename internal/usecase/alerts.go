@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/condition"
+)
+
+// AlertRule - правило предупреждения; значения являются битами маски storage.User.AlertRules
+type AlertRule int
+
+const (
+	AlertFrost AlertRule = 1 << iota
+	AlertHeat
+	AlertWind
+	AlertPrecipitation
+)
+
+// Alert - сработавшее предупреждение; значение является частью ключа сообщения в каталоге
+type Alert string
+
+const (
+	AlertIce        Alert = "ice"
+	AlertHot        Alert = "heat"
+	AlertStrongWind Alert = "wind"
+	AlertRain       Alert = "rain"
+	AlertSnow       Alert = "snow"
+	AlertStorm      Alert = "thunderstorm"
+)
+
+const (
+	// frostTemperature - температура, начиная с которой предупреждаем о заморозках и гололеде
+	frostTemperature = 0.0
+	// heatTemperature - температура, начиная с которой предупреждаем о жаре
+	heatTemperature = 30.0
+	// strongWindSpeed - скорость ветра (м/с), начиная с которой предупреждаем о сильном ветре
+	strongWindSpeed = 10.0
+)
+
+// alertRuleInfo описывает правило предупреждения для меню настроек
+type alertRuleInfo struct {
+	Rule AlertRule
+	Key  string
+}
+
+// alertRules - все правила предупреждений в порядке отображения
+var alertRules = []alertRuleInfo{
+	{Rule: AlertFrost, Key: "frost"},
+	{Rule: AlertHeat, Key: "heat"},
+	{Rule: AlertWind, Key: "wind"},
+	{Rule: AlertPrecipitation, Key: "precipitation"},
+}
+
+// CheckAlerts возвращает предупреждения по включенным правилам rules.
+// Осадки определяются по погодным условиям, а не по описанию провайдера.
+func (s *WeatherService) CheckAlerts(weather *openweather.WeatherData, rules int) []Alert {
+	var alerts []Alert
+
+	if rules&int(AlertFrost) != 0 && weather.Temperature <= frostTemperature {
+		alerts = append(alerts, AlertIce)
+	}
+	if rules&int(AlertHeat) != 0 && weather.Temperature >= heatTemperature {
+		alerts = append(alerts, AlertHot)
+	}
+	if rules&int(AlertWind) != 0 && (weather.WindSpeed >= strongWindSpeed || weather.Condition == condition.Squall) {
+		alerts = append(alerts, AlertStrongWind)
+	}
+	if rules&int(AlertPrecipitation) != 0 {
+		if weather.Condition == condition.Thunderstorm {
+			alerts = append(alerts, AlertStorm)
+		}
+		if weather.Condition.IsRain() {
+			alerts = append(alerts, AlertRain)
+		}
+		if weather.Condition.IsSnow() {
+			alerts = append(alerts, AlertSnow)
+		}
+	}
+
+	return alerts
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/astro"
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+)
+
+// moonPhaseEmoji - эмодзи восьми фаз Луны; названия фаз хранятся в каталоге сообщений
+var moonPhaseEmoji = [...]string{"🌑", "🌒", "🌓", "🌔", "🌕", "🌖", "🌗", "🌘"}
+
+// AstroData содержит астрономические данные на день
+type AstroData struct {
+	Sun astro.SunInfo
+	// DayLengthChange - изменение долготы дня по сравнению со вчерашним днем
+	DayLengthChange time.Duration
+	Moon            astro.MoonInfo
+}
+
+// GetAstro вычисляет восход, закат, долготу дня и фазу Луны для координат погоды
+// на текущий день в часовом поясе города
+func (s *WeatherService) GetAstro(weather *openweather.WeatherData) *AstroData {
+	now := time.Now().In(weather.Location)
+
+	today := astro.Sun(now, weather.Latitude, weather.Longitude)
+	yesterday := astro.Sun(now.AddDate(0, 0, -1), weather.Latitude, weather.Longitude)
+
+	return &AstroData{
+		Sun:             today,
+		DayLengthChange: today.DayLength - yesterday.DayLength,
+		Moon:            astro.Moon(now),
+	}
+}
+
+// formatClock форматирует время суток: "08:51"
+func formatClock(t time.Time) string {
+	return t.Format("15:04")
+}
+
+// formatDuration форматирует продолжительность в виде "10 ч 09 мин"
+func formatDuration(lang i18n.Lang, d time.Duration) string {
+	d = d.Round(time.Minute)
+
+	return lang.T("duration.hours_minutes", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// formatDayLengthChange форматирует изменение долготы дня относительно вчера
+func formatDayLengthChange(lang i18n.Lang, d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+
+	switch {
+	case minutes > 0:
+		return lang.T("astro.day_longer", minutes)
+	case minutes < 0:
+		return lang.T("astro.day_shorter", -minutes)
+	default:
+		return lang.T("astro.day_same")
+	}
+}
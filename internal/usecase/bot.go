@@ -1,57 +1,349 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"time"
 
+	"github.com/qrave1/DeepCakeBot/internal/broadcast"
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
 	"github.com/qrave1/DeepCakeBot/internal/storage"
 
 	tele "gopkg.in/telebot.v3"
 )
 
 type ApplicationBot struct {
-	bot            *tele.Bot
-	storage        storage.UserRepository
-	weatherService *WeatherService
+	bot             *tele.Bot
+	storage         storage.UserRepository
+	dialogs         storage.DialogRepository
+	locations       storage.LocationRepository
+	trips           storage.TripRepository
+	weatherService  *WeatherService
+	sender          *broadcast.Sender
+	inlineCache     *ttlCache[*inlineAnswer]
+	settingsScreens map[string]*settingsScreen
+	dialogFlows     map[string]*dialogFlow
+	timezone        string
+	// defaultDeliveryTime - время рассылки по умолчанию в минутах от начала суток
+	defaultDeliveryTime int
 }
 
 // NewApplicationBot создает новый сервис бота
-func NewApplicationBot(bot *tele.Bot, storage storage.UserRepository, weatherService *WeatherService) *ApplicationBot {
-	return &ApplicationBot{
-		bot:            bot,
-		storage:        storage,
-		weatherService: weatherService,
+func NewApplicationBot(
+	bot *tele.Bot,
+	storage storage.UserRepository,
+	dialogs storage.DialogRepository,
+	locations storage.LocationRepository,
+	trips storage.TripRepository,
+	weatherService *WeatherService,
+	sender *broadcast.Sender,
+	timezone string,
+	defaultDeliveryHour int,
+) *ApplicationBot {
+	s := &ApplicationBot{
+		bot:                 bot,
+		storage:             storage,
+		dialogs:             dialogs,
+		locations:           locations,
+		trips:               trips,
+		weatherService:      weatherService,
+		sender:              sender,
+		inlineCache:         newTTLCache[*inlineAnswer](inlineCacheTTL),
+		timezone:            timezone,
+		defaultDeliveryTime: defaultDeliveryHour * 60,
 	}
+
+	s.registerSettingsScreens()
+	s.registerDialogFlows()
+
+	return s
 }
 
 // RegisterHandlers регистрирует все обработчики команд и callback'ов
 func (s *ApplicationBot) RegisterHandlers() {
 	s.bot.Handle("/weather", s.handleGetWeather)
 
+	// Обработчик команды /chart
+	s.bot.Handle("/chart", s.handleChart)
+
+	// Обработчик команды /sun
+	s.bot.Handle("/sun", s.handleSun)
+
 	// Обработчик команды /start
 	s.bot.Handle("/start", s.handleStart)
 
+	// Обработчик команды /stop
+	s.bot.Handle("/stop", s.handleStop)
+
+	// Обработчик inline-запросов
+	s.bot.Handle(tele.OnQuery, s.handleInlineQuery)
+
 	// Обработчик команды /settings
 	s.bot.Handle("/settings", s.handleSettings)
 
-	// Обработчики callback для настроек
-	s.bot.Handle(&btnEnableWeather, s.handleEnableWeather)
-	s.bot.Handle(&btnDisableWeather, s.handleDisableWeather)
+	// Обработчик кнопок меню настроек
+	s.bot.Handle(&btnSettings, s.handleSettingsCallback)
+
+	// Обработчик кнопки сохранения города из /weather
+	s.bot.Handle(&btnSaveLocation, s.handleSaveLocation)
+
+	// Обработчики кнопок сохраненных мест под /weather
+	s.bot.Handle(&btnWeatherLocation, s.handleWeatherLocation)
+	s.bot.Handle(&btnAddLocation, s.handleAddLocation)
+
+	// Обработчик команды /trip и кнопок под ней
+	s.bot.Handle("/trip", s.handleTrip)
+	s.bot.Handle(&btnChangeTrip, s.handleChangeTrip)
+	s.bot.Handle(&btnCancelTrip, s.handleCancelTrip)
+
+	// Обработчики кнопок паузы под утренним сообщением
+	s.bot.Handle(&btnPause, s.handlePause)
+	s.bot.Handle(&btnResume, s.handleResume)
+
+	// Обработчик команды /cancel
+	s.bot.Handle("/cancel", s.handleCancel)
+
+	// Обработчик ответов в диалогах
+	s.bot.Handle(tele.OnText, s.handleText)
 }
 
-// SendWeatherToUser отправляет прогноз погоды конкретному пользователю
-func (s *ApplicationBot) SendWeatherToUser(ctx context.Context, chatID int64) error {
-	weather, err := s.weatherService.GetWeather(ctx)
+// BuildWeatherReport собирает данные для сообщения с погодой в указанном месте
+// с учетом настроек пользователя
+func (s *ApplicationBot) BuildWeatherReport(
+	ctx context.Context,
+	user *storage.User,
+	location openweather.Location,
+) (*WeatherReport, error) {
+	weather, err := s.weatherService.GetWeather(ctx, location, userLang(user))
 	if err != nil {
-		return fmt.Errorf("failed to get weather: %w", err)
+		return nil, fmt.Errorf("failed to get weather: %w", err)
 	}
 
-	message := s.weatherService.FormatWeatherMessage(weather)
+	report := &WeatherReport{
+		LocationName: s.weatherService.LocationName(location, weather),
+		Weather:      weather,
+		Sections:     sectionsForUser(user),
+	}
 
-	_, err = s.bot.Send(&tele.Chat{ID: chatID}, message)
+	// Дополнительные данные запрашиваются только для включенных разделов
+	for _, section := range report.Sections {
+		switch section {
+		case SectionComparison:
+			report.Comparison, err = s.weatherService.CompareWithYesterday(ctx, weather)
+			if err != nil {
+				log.Printf("Failed to compare weather with yesterday: %v", err)
+			}
+		case SectionClothing:
+			report.Precipitation, err = s.weatherService.GetPrecipitationWindows(ctx, weather)
+			if err != nil {
+				log.Printf("Failed to get precipitation windows: %v", err)
+			}
+		case SectionAlerts:
+			report.Alerts = s.weatherService.CheckAlerts(weather, user.AlertRules)
+		case SectionAstro:
+			report.Astro = s.weatherService.GetAstro(weather)
+		case SectionAir:
+			report.Air, err = s.weatherService.GetAirQuality(ctx, weather)
+			if err != nil {
+				log.Printf("Failed to get air quality: %v", err)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// MorningDelivery - параметры отправки утреннего прогноза
+type MorningDelivery struct {
+	// Slot - время рассылки по расписанию: по нему считается день поездки, даже если прогноз опоздал
+	Slot time.Time
+	// Notice, если не пустое, добавляется перед прогнозом
+	Notice string
+	// Sent - сколько сообщений прогноза уже отправлено предыдущими попытками; они пропускаются
+	Sent int
+	// Messages - сообщения прогноза, собранные предыдущей попыткой; пусто - собрать заново
+	Messages []string
+	// Prepare, если задан, вызывается с собранными сообщениями перед отправкой первого из них,
+	// чтобы повторная попытка отправила те же сообщения. Ошибка прерывает отправку.
+	Prepare func(messages []string) error
+	// Progress, если задан, вызывается после отправки каждого сообщения: sent - сколько сообщений
+	// отправлено, messageID - ID последнего из них
+	Progress func(sent, messageID int)
+}
+
+// SendWeatherToUser отправляет утренний прогноз погоды конкретному пользователю:
+// основной город и сохраненные места, отмеченные для утренней рассылки.
+// Возвращает ID последнего отправленного сообщения или 0, если все сообщения уже были отправлены.
+func (s *ApplicationBot) SendWeatherToUser(
+	ctx context.Context,
+	user *storage.User,
+	delivery MorningDelivery,
+) (int, error) {
+	messages := delivery.Messages
+	if len(messages) == 0 {
+		var err error
+		if messages, err = s.buildMorningMessages(ctx, user); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.sendMorningMessages(ctx, user, delivery, messages)
+}
+
+// sendMorningMessages отправляет утренние сообщения, начиная с первого неотправленного;
+// notice добавляется перед первым сообщением, под последним - кнопки паузы рассылки.
+// Собранные заново сообщения передаются в delivery.Prepare до отправки.
+// Возвращает ID последнего отправленного сообщения.
+func (s *ApplicationBot) sendMorningMessages(
+	ctx context.Context,
+	user *storage.User,
+	delivery MorningDelivery,
+	messages []string,
+) (int, error) {
+	if len(delivery.Messages) == 0 && delivery.Prepare != nil {
+		if err := delivery.Prepare(messages); err != nil {
+			return 0, fmt.Errorf("failed to prepare messages for %d: %w", user.ChatID, err)
+		}
+	}
+
+	messages = slices.Clone(messages)
+	if delivery.Notice != "" && len(messages) > 0 {
+		messages[0] = delivery.Notice + "\n\n" + messages[0]
+	}
+
+	messageID := 0
+
+	for i := delivery.Sent; i < len(messages); i++ {
+		var opts []interface{}
+		if i == len(messages)-1 {
+			opts = append(opts, pauseKeyboard(userLang(user)))
+		}
+
+		sent, err := s.send(ctx, user.ChatID, messages[i], opts...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to send message to %d: %w", user.ChatID, err)
+		}
+		messageID = sent.ID
+
+		if delivery.Progress != nil {
+			delivery.Progress(i+1, messageID)
+		}
+	}
+
+	return messageID, nil
+}
+
+// SendChartToUser отправляет график прогноза для места location конкретному пользователю
+func (s *ApplicationBot) SendChartToUser(ctx context.Context, user *storage.User, location openweather.Location) error {
+	chart, err := s.weatherService.RenderForecastChart(ctx, location, unitsForUser(user))
 	if err != nil {
+		return fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	photo := &tele.Photo{
+		File:    tele.FromReader(bytes.NewReader(chart.Bytes())),
+		Caption: s.weatherService.FormatChartCaption(userLang(user), unitsForUser(user), location.Name),
+	}
+
+	if _, err := s.send(ctx, user.ChatID, photo); err != nil {
+		return fmt.Errorf("failed to send chart to %d: %w", user.ChatID, err)
+	}
+
+	return nil
+}
+
+// SendTextToUser отправляет текстовое сообщение конкретному пользователю
+func (s *ApplicationBot) SendTextToUser(ctx context.Context, chatID int64, message string) error {
+	if _, err := s.send(ctx, chatID, message); err != nil {
 		return fmt.Errorf("failed to send message to %d: %w", chatID, err)
 	}
 
 	return nil
 }
+
+// send отправляет сообщение в чат chatID через отправителя с лимитами Telegram
+func (s *ApplicationBot) send(
+	ctx context.Context,
+	chatID int64,
+	what interface{},
+	opts ...interface{},
+) (*tele.Message, error) {
+	var sent *tele.Message
+
+	err := s.sender.Send(
+		ctx, chatID, func() error {
+			var err error
+			sent, err = s.bot.Send(&tele.Chat{ID: chatID}, what, opts...)
+			return err
+		},
+	)
+
+	return sent, err
+}
+
+// unreachableReason определяет по ошибке отправки, что пользователь больше не может получать сообщения:
+// заблокировал бота, удалил чат или аккаунт. Возвращает причину для storage.User.InactiveReason.
+func unreachableReason(err error) (string, bool) {
+	switch {
+	case errors.Is(err, tele.ErrBlockedByUser):
+		return storage.InactiveReasonBlocked, true
+	case errors.Is(err, tele.ErrChatNotFound):
+		return storage.InactiveReasonChatNotFound, true
+	case errors.Is(err, tele.ErrUserIsDeactivated):
+		return storage.InactiveReasonDeactivated, true
+	default:
+		return "", false
+	}
+}
+
+// floodDelay возвращает, через сколько Telegram разрешает повторить отправку, если err - FloodError
+func floodDelay(err error) (time.Duration, bool) {
+	var flood tele.FloodError
+	if !errors.As(err, &flood) {
+		return 0, false
+	}
+
+	return time.Duration(flood.RetryAfter) * time.Second, true
+}
+
+// DeactivateIfUnreachable отключает рассылки пользователю, до которого сообщения больше не доходят.
+// Возвращает true, если пользователь отключен; он вернется, отправив /start.
+func (s *ApplicationBot) DeactivateIfUnreachable(ctx context.Context, chatID int64, err error) bool {
+	reason, ok := unreachableReason(err)
+	if !ok {
+		return false
+	}
+
+	if err := s.storage.DeactivateUser(ctx, chatID, reason); err != nil {
+		log.Printf("Failed to deactivate user %d: %v", chatID, err)
+		return false
+	}
+
+	log.Printf("User %d is unreachable (%s), deactivated", chatID, reason)
+
+	return true
+}
+
+// now возвращает текущее время в часовом поясе бота
+func (s *ApplicationBot) now() time.Time {
+	timezone, err := time.LoadLocation(s.timezone)
+	if err != nil {
+		return time.Now()
+	}
+
+	return time.Now().In(timezone)
+}
+
+// userLang возвращает язык сообщений пользователя: выбранный в настройках или язык Telegram
+func userLang(user *storage.User) i18n.Lang {
+	if lang, ok := i18n.Parse(user.Language); ok {
+		return lang
+	}
+
+	return i18n.FromTelegram(user.LanguageCode)
+}
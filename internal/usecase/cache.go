@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache - потокобезопасный кэш значений с ограниченным временем жизни
+type ttlCache[V any] struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]ttlCacheItem[V]
+}
+
+type ttlCacheItem[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// newTTLCache создает кэш, в котором значения живут ttl
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:   ttl,
+		items: make(map[string]ttlCacheItem[V]),
+	}
+}
+
+// Get возвращает значение по ключу, если оно есть и не устарело
+func (c *ttlCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok || time.Now().After(item.expiresAt) {
+		var zero V
+		return zero, false
+	}
+
+	return item.value, true
+}
+
+// Set сохраняет значение по ключу и удаляет устаревшие значения
+func (c *ttlCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, item := range c.items {
+		if now.After(item.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+
+	c.items[key] = ttlCacheItem[V]{
+		value:     value,
+		expiresAt: now.Add(c.ttl),
+	}
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/chart"
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+)
+
+// chartHorizon - период, на который строится график прогноза
+const chartHorizon = 48 * time.Hour
+
+// RenderForecastChart рисует PNG-график температуры в единицах u и осадков на ближайшие 48 часов
+func (s *WeatherService) RenderForecastChart(ctx context.Context, location openweather.Location, u units.Preferences) (
+	*bytes.Buffer,
+	error,
+) {
+	forecast, err := s.GetForecast(ctx, location, i18n.Default)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	var points []chart.Point
+	for _, entry := range forecast {
+		if len(points) > 0 && entry.Time.Sub(points[0].Time) > chartHorizon {
+			break
+		}
+
+		points = append(
+			points, chart.Point{
+				Time:          entry.Time,
+				Temperature:   u.Temperature.FromCelsius(entry.Temperature),
+				Precipitation: entry.Rain + entry.Snow,
+			},
+		)
+	}
+
+	var buf bytes.Buffer
+	if err := chart.Render(&buf, points); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return &buf, nil
+}
+
+// FormatChartCaption возвращает подпись к графику прогноза
+func (s *WeatherService) FormatChartCaption(lang i18n.Lang, u units.Preferences, locationName string) string {
+	return lang.T("chart.caption", unitSymbol(lang, u.Temperature), locationName)
+}
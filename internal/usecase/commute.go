@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+// CommuteForecast содержит прогноз на дорогу: у дома при выезде и у работы при возвращении
+type CommuteForecast struct {
+	Departure CommutePoint
+	Return    CommutePoint
+}
+
+// CommutePoint - прогноз в месте дороги на нужный час
+type CommutePoint struct {
+	// Minute - время в минутах от начала суток
+	Minute       int
+	LocationName string
+	// Entry - интервал прогноза, ближайший к нужному времени
+	Entry openweather.ForecastEntry
+}
+
+// commuteLocation возвращает место дороги по ID: mainLocationID - основной город,
+// иначе сохраненное место пользователя
+func (s *ApplicationBot) commuteLocation(ctx context.Context, user *storage.User, id uint) (
+	openweather.Location,
+	error,
+) {
+	if id == mainLocationID {
+		return s.weatherService.LocationForUser(user), nil
+	}
+
+	location, err := s.locations.GetUserLocation(ctx, user.ChatID, id)
+	if err != nil {
+		return openweather.Location{}, err
+	}
+
+	return userLocation(location), nil
+}
+
+// BuildCommuteForecast собирает прогноз на дорогу пользователя. Если время возвращения
+// сегодня уже прошло, прогноз строится на завтра.
+func (s *ApplicationBot) BuildCommuteForecast(ctx context.Context, user *storage.User) (*CommuteForecast, error) {
+	now := s.now()
+	day := startOfDay(now)
+	if now.After(day.Add(time.Duration(user.CommuteReturn) * time.Minute)) {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	departure, err := s.commutePoint(ctx, user, user.CommuteHome, day, user.CommuteDeparture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get departure forecast: %w", err)
+	}
+
+	ret, err := s.commutePoint(ctx, user, user.CommuteWork, day, user.CommuteReturn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return forecast: %w", err)
+	}
+
+	return &CommuteForecast{Departure: *departure, Return: *ret}, nil
+}
+
+// commutePoint получает прогноз в месте locationID на время minute дня day
+func (s *ApplicationBot) commutePoint(
+	ctx context.Context,
+	user *storage.User,
+	locationID uint,
+	day time.Time,
+	minute int,
+) (*CommutePoint, error) {
+	location, err := s.commuteLocation(ctx, user, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast, err := s.weatherService.GetForecast(ctx, location, userLang(user))
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := nearestForecastEntry(forecast, day.Add(time.Duration(minute)*time.Minute))
+	if !ok {
+		return nil, fmt.Errorf("empty forecast for %s", location.Name)
+	}
+
+	return &CommutePoint{
+		Minute:       minute,
+		LocationName: location.Name,
+		Entry:        entry,
+	}, nil
+}
+
+// nearestForecastEntry находит интервал прогноза, ближайший ко времени at
+func nearestForecastEntry(entries []openweather.ForecastEntry, at time.Time) (openweather.ForecastEntry, bool) {
+	var nearest openweather.ForecastEntry
+	var nearestDistance time.Duration
+
+	for i, entry := range entries {
+		distance := entry.Time.Sub(at).Abs()
+		if i == 0 || distance < nearestDistance {
+			nearest = entry
+			nearestDistance = distance
+		}
+	}
+
+	return nearest, len(entries) > 0
+}
+
+// commuteLocationIDs возвращает ID мест, доступных для дороги: основной город и сохраненные места
+func (s *ApplicationBot) commuteLocationIDs(ctx context.Context, user *storage.User) []uint {
+	ids := []uint{mainLocationID}
+
+	locations, err := s.locations.GetUserLocations(ctx, user.ChatID)
+	if err != nil {
+		log.Printf("Failed to get locations of user %d: %v", user.ChatID, err)
+	}
+
+	for _, location := range locations {
+		ids = append(ids, location.ID)
+	}
+
+	return ids
+}
+
+// commuteLocationName возвращает название места дороги для настроек
+func (s *ApplicationBot) commuteLocationName(ctx context.Context, user *storage.User, id uint) string {
+	location, err := s.commuteLocation(ctx, user, id)
+	if err != nil {
+		return s.weatherService.LocationForUser(user).Name
+	}
+
+	return location.Name
+}
@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/queue"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+// Типы ежедневных задач в очереди. Задачи выполняются в час сводок, ключ задачи содержит дату,
+// поэтому задача за день добавляется и выполняется один раз, даже если бот не работал в это время.
+const (
+	// jobDigestSchedule - рассылка сводок за день: добавляет задачи отправки сводок подписчикам
+	jobDigestSchedule = "digest.schedule"
+	// jobDigestDelivery - отправка одной сводки одному пользователю
+	jobDigestDelivery = "digest.delivery"
+	// jobTripsProcess - прогнозы для сборов и завершение закончившихся поездок
+	jobTripsProcess = "trips.process"
+	// jobPrune - удаление устаревших данных
+	jobPrune = "maintenance.prune"
+)
+
+// Виды сводок
+const (
+	digestWeekly  = "weekly"
+	digestMonthly = "monthly"
+)
+
+const (
+	// dateLayout - формат даты в ключах и параметрах ежедневных задач
+	dateLayout = "2006-01-02"
+	// digestDeliveryAttempts - сколько раз повторяется отправка сводки
+	digestDeliveryAttempts = 5
+	// digestCacheTTL - сколько хранится сводка, собранная для места: ее отправляют всем подписчикам места
+	digestCacheTTL = time.Hour
+)
+
+// dailyPayload - параметры ежедневной задачи
+type dailyPayload struct {
+	// Date - день задачи по времени бота в формате dateLayout
+	Date string `json:"date"`
+}
+
+// digestDeliveryPayload - параметры задачи jobDigestDelivery
+type digestDeliveryPayload struct {
+	ChatID int64  `json:"chat_id"`
+	Date   string `json:"date"`
+	Kind   string `json:"kind"`
+}
+
+// registerDailyJobs регистрирует обработчики ежедневных задач
+func (s *Scheduler) registerDailyJobs() {
+	// Цепочки ежедневных задач не должны обрываться, поэтому их задачи повторяются без ограничения
+	s.queue.Register(jobDigestSchedule, queue.Options{}, s.dailyHandler(jobDigestSchedule, s.scheduleDigests))
+	s.queue.Register(jobTripsProcess, queue.Options{}, s.dailyHandler(jobTripsProcess, s.processTrips))
+	s.queue.Register(jobPrune, queue.Options{}, s.dailyHandler(jobPrune, s.prune))
+
+	s.queue.Register(
+		jobDigestDelivery,
+		queue.Options{MaxAttempts: digestDeliveryAttempts, RateLimit: s.broadcastLimit},
+		s.handleDigestDelivery,
+	)
+}
+
+// enqueueDailyJobs добавляет ежедневные задачи за день date. Повторное добавление задач
+// за тот же день ничего не меняет.
+func (s *Scheduler) enqueueDailyJobs(ctx context.Context, date time.Time) error {
+	for _, jobType := range []string{jobDigestSchedule, jobTripsProcess, jobPrune} {
+		if err := s.enqueueDailyJob(ctx, jobType, date); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueueDailyJob добавляет задачу jobType за день date, которая выполнится в час сводок
+func (s *Scheduler) enqueueDailyJob(ctx context.Context, jobType string, date time.Time) error {
+	day := date.Format(dateLayout)
+	runAt := time.Date(date.Year(), date.Month(), date.Day(), s.digestHour, 0, 0, 0, s.timezone)
+
+	job, err := queue.NewJob(jobType, jobType+":"+day, runAt, dailyPayload{Date: day})
+	if err != nil {
+		return err
+	}
+
+	return s.queue.Enqueue(ctx, job)
+}
+
+// dailyHandler возвращает обработчик ежедневной задачи jobType: он добавляет задачу на следующий день
+// и выполняет run для дня задачи. Если бот не работал несколько дней, задачи за пропущенные дни
+// не накапливаются: следующая задача добавляется не раньше, чем на сегодня.
+func (s *Scheduler) dailyHandler(jobType string, run func(ctx context.Context, date time.Time) error) queue.Handler {
+	return func(ctx context.Context, job *storage.Job) error {
+		var payload dailyPayload
+		if err := queue.Decode(job, &payload); err != nil {
+			return err
+		}
+
+		date, err := time.ParseInLocation(dateLayout, payload.Date, s.timezone)
+		if err != nil {
+			return queue.Permanent(fmt.Errorf("invalid %s job date %q: %w", jobType, payload.Date, err))
+		}
+
+		next := date.AddDate(0, 0, 1)
+		if today := s.today(); next.Before(today) {
+			next = today
+		}
+
+		if err := s.enqueueDailyJob(ctx, jobType, next); err != nil {
+			return err
+		}
+
+		return run(ctx, date)
+	}
+}
+
+// today возвращает начало текущего дня по времени бота
+func (s *Scheduler) today() time.Time {
+	return dateOf(time.Now().In(s.timezone))
+}
+
+// scheduleDigests добавляет задачи отправки сводок подписчикам: еженедельной по воскресеньям
+// и итогов месяца в последний день месяца. Сводки за прошедший день уже не отправляются.
+func (s *Scheduler) scheduleDigests(ctx context.Context, date time.Time) error {
+	if !date.Equal(s.today()) {
+		log.Printf("Digests for %s are outdated, skipping them", date.Format(dateLayout))
+		return nil
+	}
+
+	var kinds []string
+	if date.Weekday() == time.Sunday {
+		kinds = append(kinds, digestWeekly)
+	}
+	if date.AddDate(0, 0, 1).Day() == 1 {
+		kinds = append(kinds, digestMonthly)
+	}
+
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	users, err := s.storage.GetAllDigestUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get digest users: %w", err)
+	}
+
+	day := date.Format(dateLayout)
+
+	var jobs []storage.Job
+	for _, user := range users {
+		for _, kind := range kinds {
+			job, err := queue.NewJob(
+				jobDigestDelivery,
+				fmt.Sprintf("%s:%s:%s:%d", jobDigestDelivery, day, kind, user.ChatID),
+				time.Now(),
+				digestDeliveryPayload{ChatID: user.ChatID, Date: day, Kind: kind},
+			)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	if err := s.queue.Enqueue(ctx, jobs...); err != nil {
+		return err
+	}
+
+	log.Printf("Scheduled %d digests for %d users", len(jobs), len(users))
+
+	return nil
+}
+
+// handleDigestDelivery отправляет сводку по задаче jobDigestDelivery
+func (s *Scheduler) handleDigestDelivery(ctx context.Context, job *storage.Job) error {
+	var payload digestDeliveryPayload
+	if err := queue.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	// Сводка, которую не успели отправить в свой день, уже не нужна
+	if payload.Date != s.today().Format(dateLayout) {
+		return nil
+	}
+
+	user, err := s.storage.GetUser(ctx, payload.ChatID)
+	if err != nil {
+		return err
+	}
+
+	// С момента планирования пользователь мог отписаться
+	if !user.Active || !user.DigestEnabled {
+		return nil
+	}
+
+	message, err := s.formatDigest(ctx, user, payload.Kind)
+	if err != nil {
+		return err
+	}
+
+	if err := s.applicationBot.SendTextToUser(ctx, user.ChatID, message); err != nil {
+		log.Printf("Failed to send digest to user %d: %v", user.ChatID, err)
+
+		if s.applicationBot.DeactivateIfUnreachable(ctx, user.ChatID, err) {
+			return queue.Permanent(err)
+		}
+
+		if delay, ok := floodDelay(err); ok {
+			return queue.RetryAfter(err, delay)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// formatDigest собирает сводку вида kind для места пользователя и форматирует ее на языке
+// и в единицах пользователя. Собранная сводка переиспользуется для других подписчиков места.
+func (s *Scheduler) formatDigest(ctx context.Context, user *storage.User, kind string) (string, error) {
+	now := time.Now().In(s.timezone)
+	location := s.weatherService.LocationForUser(user)
+	key := digestCacheKey(now, location)
+
+	lang := userLang(user)
+	u := unitsForUser(user)
+
+	switch kind {
+	case digestWeekly:
+		digest, ok := s.weeklyDigests.Get(key)
+		if !ok {
+			var err error
+			if digest, err = s.weatherService.BuildWeeklyDigest(ctx, location, now); err != nil {
+				return "", fmt.Errorf("failed to build weekly digest for %s: %w", location.Name, err)
+			}
+			s.weeklyDigests.Set(key, digest)
+		}
+
+		return s.weatherService.FormatWeeklyDigest(lang, u, digest), nil
+	case digestMonthly:
+		digest, ok := s.monthlyDigests.Get(key)
+		if !ok {
+			var err error
+			if digest, err = s.weatherService.BuildMonthlyDigest(ctx, location, now); err != nil {
+				return "", fmt.Errorf("failed to build monthly digest for %s: %w", location.Name, err)
+			}
+			s.monthlyDigests.Set(key, digest)
+		}
+
+		return s.weatherService.FormatMonthlyDigest(lang, u, digest), nil
+	default:
+		return "", queue.Permanent(fmt.Errorf("unknown digest kind %q", kind))
+	}
+}
+
+// digestCacheKey возвращает ключ сводки для места location за день now
+func digestCacheKey(now time.Time, location openweather.Location) string {
+	return fmt.Sprintf(
+		"%s|%s,%s|%f,%f",
+		now.Format(dateLayout), location.Name, location.CountryCode, location.Latitude, location.Longitude,
+	)
+}
+
+// prune удаляет устаревшие наблюдения, диалоги, записи журнала рассылки и задачи очереди
+func (s *Scheduler) prune(ctx context.Context, _ time.Time) error {
+	var errs []error
+
+	if err := s.weatherService.PruneObservations(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to prune weather observations: %w", err))
+	}
+
+	if err := s.applicationBot.PruneDialogs(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to prune dialogs: %w", err))
+	}
+
+	if err := s.pruneDeliveries(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to prune deliveries: %w", err))
+	}
+
+	if err := s.queue.Prune(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to prune jobs: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,353 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/queue"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+// Типы задач утренней рассылки в очереди
+const (
+	// jobWeatherSchedule - планирование рассылки: записывает прогнозы слота в журнал
+	// и добавляет задачу для следующего слота
+	jobWeatherSchedule = "weather.schedule"
+	// jobWeatherDelivery - отправка прогноза одному пользователю
+	jobWeatherDelivery = "weather.delivery"
+)
+
+// broadcastRateLimit - название общего лимита скорости задач рассылки: прогнозы и сводки
+// отправляются всеми репликами вместе не быстрее BROADCAST_RATE задач в секунду
+const broadcastRateLimit = "broadcast"
+
+const (
+	// deliveryRetryBaseDelay, deliveryRetryMaxDelay - задержка перед повторной отправкой прогноза:
+	// удваивается с каждой попыткой, но не превышает максимум
+	deliveryRetryBaseDelay = time.Minute
+	deliveryRetryMaxDelay  = 30 * time.Minute
+	// deliveryRetention - срок хранения журнала рассылки
+	deliveryRetention = 30 * 24 * time.Hour
+	// lateDeliveryDelay - через сколько после времени рассылки прогноз отправляется с пометкой об опоздании
+	lateDeliveryDelay = 15 * time.Minute
+	// deliveryStaleAfter - через сколько без отметок об отправленных сообщениях запись в состоянии
+	// "отправляется" считается брошенной упавшим обработчиком и может быть забрана снова
+	deliveryStaleAfter = 5 * time.Minute
+)
+
+// errDeliveryInProgress - прогноз сейчас отправляет другой обработчик
+var errDeliveryInProgress = errors.New("delivery is in progress")
+
+// deliveryRetryDelay возвращает задержку перед следующей попыткой после attempts неудачных
+var deliveryRetryDelay = queue.ExponentialBackoff(deliveryRetryBaseDelay, deliveryRetryMaxDelay)
+
+// weatherSchedulePayload - параметры задачи jobWeatherSchedule
+type weatherSchedulePayload struct {
+	Slot time.Time `json:"slot"`
+}
+
+// weatherDeliveryPayload - параметры задачи jobWeatherDelivery
+type weatherDeliveryPayload struct {
+	ChatID int64     `json:"chat_id"`
+	Slot   time.Time `json:"slot"`
+}
+
+// registerDeliveryJobs регистрирует обработчики задач утренней рассылки
+func (s *Scheduler) registerDeliveryJobs() {
+	// Цепочка планирования не должна обрываться, поэтому ее задачи повторяются без ограничения
+	s.queue.Register(
+		jobWeatherSchedule,
+		queue.Options{
+			Backoff: func(int) time.Duration {
+				return time.Minute
+			},
+		},
+		s.handleWeatherSchedule,
+	)
+
+	// Число попыток отправки ограничено окном повторов в retryDelivery
+	s.queue.Register(
+		jobWeatherDelivery,
+		queue.Options{Backoff: deliveryRetryDelay, RateLimit: s.broadcastLimit},
+		s.handleWeatherDelivery,
+	)
+}
+
+// newDelivery создает запись журнала рассылки для отправки прогноза в slot;
+// tripID - поездка пользователя или 0
+func newDelivery(chatID int64, slot time.Time, tripID uint) storage.Delivery {
+	return storage.Delivery{
+		ChatID: chatID,
+		Slot:   slot,
+		TripID: tripID,
+		Status: storage.DeliveryStatusPending,
+	}
+}
+
+// enqueueWeatherSchedule добавляет задачу планирования рассылки для слота slot.
+// Ключ задачи зависит только от слота, поэтому задача для слота добавляется один раз.
+func (s *Scheduler) enqueueWeatherSchedule(ctx context.Context, slot time.Time) error {
+	job, err := queue.NewJob(
+		jobWeatherSchedule,
+		fmt.Sprintf("%s:%d", jobWeatherSchedule, slot.Unix()),
+		slot,
+		weatherSchedulePayload{Slot: slot},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.queue.Enqueue(ctx, job)
+}
+
+// handleWeatherSchedule планирует рассылку для слотов от слота задачи до текущего и добавляет
+// задачу для следующего слота. Слоты старше окна catchUpWindow пропускаются. Планирование
+// слота сверяется с журналом рассылки, поэтому повторно пройденные слоты не создают дублей.
+func (s *Scheduler) handleWeatherSchedule(ctx context.Context, job *storage.Job) error {
+	var payload weatherSchedulePayload
+	if err := queue.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	now := time.Now().In(s.timezone).Truncate(time.Minute)
+	from := payload.Slot.In(s.timezone)
+
+	if earliest := now.Add(-s.catchUpWindow); from.Before(earliest) {
+		// Задача, добавленная при запуске, начинается с границы окна и к выполнению может отстать от нее на минуту
+		if earliest.Sub(from) > time.Minute {
+			log.Printf("Weather slots before %s are too old to catch up, skipping them", earliest.Format("2006-01-02 15:04"))
+		}
+		from = earliest
+	}
+
+	if now.Sub(from) > time.Minute {
+		log.Printf("Catching up weather deliveries missed since %s", from.Format("2006-01-02 15:04"))
+	}
+
+	for slot := from; !slot.After(now); slot = slot.Add(time.Minute) {
+		if err := s.scheduleWeatherForSlot(ctx, slot); err != nil {
+			return err
+		}
+	}
+
+	return s.enqueueWeatherSchedule(ctx, now.Add(time.Minute))
+}
+
+// handleWeatherDelivery отправляет прогноз по задаче jobWeatherDelivery
+func (s *Scheduler) handleWeatherDelivery(ctx context.Context, job *storage.Job) error {
+	var payload weatherDeliveryPayload
+	if err := queue.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	delivery, err := s.deliveries.GetDelivery(ctx, payload.ChatID, payload.Slot)
+	if err != nil {
+		return err
+	}
+
+	// Прогноз уже отправлен или отменен, например задача повторилась после перезапуска
+	if delivery.Status != storage.DeliveryStatusPending && delivery.Status != storage.DeliveryStatusSending {
+		return nil
+	}
+
+	return s.sendDelivery(ctx, delivery, job.Attempts, time.Now().In(s.timezone))
+}
+
+// sendDelivery отправляет прогноз по записи журнала рассылки и сохраняет результат.
+// attempt - номер попытки. Ошибка означает, что отправку нужно повторить; окончательные
+// ошибки обернуты в queue.Permanent.
+func (s *Scheduler) sendDelivery(ctx context.Context, delivery *storage.Delivery, attempt int, now time.Time) error {
+	// Прогноз, пропущенный слишком давно, уже не нужен
+	if now.Sub(delivery.Slot) > max(s.retryWindow, s.catchUpWindow) {
+		return s.failDelivery(ctx, delivery, errors.New("delivery window expired"))
+	}
+
+	user, err := s.storage.GetUser(ctx, delivery.ChatID)
+	if err != nil {
+		log.Printf("Failed to get user %d for delivery: %v", delivery.ChatID, err)
+		return s.retryDelivery(ctx, delivery, attempt, err, now)
+	}
+
+	// С момента записи пользователь мог отписаться или приостановить рассылку. Пауза проверяется
+	// на время рассылки: пропущенный прогноз не отправляется повторной попыткой после паузы.
+	if !user.Active || !user.WeatherEnabled || isPaused(user, delivery.Slot) {
+		if err := s.deliveries.MarkDeliverySkipped(ctx, delivery.ID, "weather delivery disabled"); err != nil {
+			log.Printf("Failed to mark delivery to user %d skipped: %v", user.ChatID, err)
+		}
+		return nil
+	}
+
+	// Запись забирается перед отправкой, чтобы прогноз не отправили два обработчика: задача
+	// может выполниться повторно, если истекла ее аренда. Забрать запись не удалось - прогноз
+	// отправляется сейчас, и задача повторится позже, когда отправка закончится или будет брошена.
+	claimed, err := s.deliveries.ClaimDelivery(ctx, delivery.ID, now.Add(-deliveryStaleAfter))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errDeliveryInProgress
+	}
+
+	trip := s.deliveryTrip(ctx, delivery)
+	location := s.weatherService.LocationForUser(user)
+
+	// Прогноз после перезапуска или повторной попытки может прийти заметно позже обычного
+	scheduled := delivery.Slot
+	if trip != nil {
+		scheduled = scheduled.In(tripZone(trip))
+	}
+
+	// Сообщения сохраняются в журнале перед первой отправкой, каждое отправленное сообщение
+	// отмечается, и повторная попытка продолжает с первого неотправленного из тех же сообщений,
+	// поэтому пользователь не получит часть прогноза дважды и ничего не пропустит
+	morning := MorningDelivery{
+		Slot:     delivery.Slot,
+		Sent:     delivery.SentParts,
+		Messages: deliveryMessages(delivery),
+		Prepare: func(messages []string) error {
+			data, err := json.Marshal(messages)
+			if err != nil {
+				return err
+			}
+
+			return s.deliveries.SaveDeliveryMessages(ctx, delivery.ID, string(data))
+		},
+		Progress: func(sent, messageID int) {
+			// Отправленное сообщение отмечается и при остановке бота
+			err := s.deliveries.MarkDeliveryProgress(context.WithoutCancel(ctx), delivery.ID, sent, messageID)
+			if err != nil {
+				log.Printf("Failed to save delivery progress to user %d: %v", delivery.ChatID, err)
+			}
+		},
+	}
+
+	if now.Sub(delivery.Slot) >= lateDeliveryDelay {
+		morning.Notice = userLang(user).T("delivery.late", scheduled.Format("15:04"))
+	}
+
+	var messageID int
+	if trip != nil {
+		location = tripLocation(trip)
+		messageID, err = s.applicationBot.SendTripWeatherToUser(ctx, user, trip, morning)
+	} else {
+		messageID, err = s.applicationBot.SendWeatherToUser(ctx, user, morning)
+	}
+
+	if err != nil {
+		log.Printf("Failed to send weather to user %d: %v", user.ChatID, err)
+
+		if s.applicationBot.DeactivateIfUnreachable(ctx, user.ChatID, err) {
+			return s.failDelivery(ctx, delivery, err)
+		}
+
+		return s.retryDelivery(ctx, delivery, attempt, err, now)
+	}
+
+	// Все сообщения были отправлены предыдущей попыткой, которая не успела отметить запись
+	if messageID == 0 {
+		messageID = delivery.MessageID
+	}
+
+	if err := s.deliveries.MarkDeliverySent(ctx, delivery.ID, messageID); err != nil {
+		log.Printf("Failed to mark delivery to user %d sent: %v", user.ChatID, err)
+	}
+
+	// График дополняет прогноз, поэтому ошибка его отправки не повторяется
+	if user.ChartEnabled {
+		if err := s.applicationBot.SendChartToUser(ctx, user, location); err != nil {
+			log.Printf("Failed to send chart to user %d: %v", user.ChatID, err)
+		}
+	}
+
+	return nil
+}
+
+// deliveryMessages возвращает сообщения прогноза, сохраненные в записи журнала предыдущей попыткой
+func deliveryMessages(delivery *storage.Delivery) []string {
+	if delivery.Messages == "" {
+		return nil
+	}
+
+	var messages []string
+	if err := json.Unmarshal([]byte(delivery.Messages), &messages); err != nil {
+		log.Printf("Failed to decode delivery messages to user %d: %v", delivery.ChatID, err)
+		return nil
+	}
+
+	return messages
+}
+
+// retryDelivery записывает неудачную попытку attempt с ошибкой sendErr. Если время на повторы
+// не истекло, возвращает sendErr с задержкой повтора для очереди, иначе отмечает запись неудачной.
+func (s *Scheduler) retryDelivery(
+	ctx context.Context,
+	delivery *storage.Delivery,
+	attempt int,
+	sendErr error,
+	now time.Time,
+) error {
+	// После FloodError отправка повторяется, как только Telegram разрешит
+	delay, flooded := floodDelay(sendErr)
+	if !flooded {
+		delay = deliveryRetryDelay(attempt)
+	}
+
+	if now.Add(delay).Sub(delivery.Slot) > s.retryWindow {
+		return s.failDelivery(ctx, delivery, sendErr)
+	}
+
+	if err := s.deliveries.MarkDeliveryRetry(ctx, delivery.ID, sendErr.Error()); err != nil {
+		log.Printf("Failed to schedule delivery retry to user %d: %v", delivery.ChatID, err)
+	}
+
+	return queue.RetryAfter(sendErr, delay)
+}
+
+// failDelivery отмечает запись неудачной; отправка больше не повторяется
+func (s *Scheduler) failDelivery(ctx context.Context, delivery *storage.Delivery, sendErr error) error {
+	if err := s.deliveries.MarkDeliveryFailed(ctx, delivery.ID, sendErr.Error()); err != nil {
+		log.Printf("Failed to mark delivery to user %d failed: %v", delivery.ChatID, err)
+	}
+
+	return queue.Permanent(sendErr)
+}
+
+// deliveryTrip возвращает поездку, для места которой записан прогноз. Если поездку с тех пор
+// отменили или изменили, прогноз отправляется для дома.
+func (s *Scheduler) deliveryTrip(ctx context.Context, delivery *storage.Delivery) *storage.Trip {
+	if delivery.TripID == 0 {
+		return nil
+	}
+
+	trip, err := s.trips.GetTrip(ctx, delivery.ChatID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrTripNotFound) {
+			log.Printf("Failed to get trip of user %d: %v", delivery.ChatID, err)
+		}
+		return nil
+	}
+
+	if trip.ID != delivery.TripID || !tripActive(trip, delivery.Slot) {
+		return nil
+	}
+
+	return trip
+}
+
+// pruneDeliveries удаляет записи журнала рассылки старше срока хранения
+func (s *Scheduler) pruneDeliveries(ctx context.Context) error {
+	deleted, err := s.deliveries.DeleteDeliveriesBefore(ctx, time.Now().Add(-deliveryRetention))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("Pruned %d weather deliveries older than %s", deleted, deliveryRetention)
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// dialogTimeout - время ожидания ответа пользователя, после которого диалог прерывается
+const dialogTimeout = 10 * time.Minute
+
+// dialogEnd - следующий шаг, завершающий диалог
+const dialogEnd = ""
+
+// dialogInput - ответ пользователя на шаге диалога
+type dialogInput struct {
+	User *storage.User
+	Lang i18n.Lang
+	Text string
+	// Data - данные, собранные на предыдущих шагах; изменения сохраняются при переходе на следующий шаг
+	Data map[string]string
+}
+
+// dialogStep описывает шаг диалога
+type dialogStep struct {
+	// prompt формирует вопрос, который бот задает при переходе на шаг
+	prompt func(lang i18n.Lang, data map[string]string) string
+	// options - ключи вариантов ответа в каталоге сообщений, которые показываются кнопками клавиатуры
+	options []string
+	// handle обрабатывает ответ и возвращает следующий шаг (dialogEnd - завершить диалог)
+	// и сообщение для пользователя. Ошибка dialogRetry оставляет пользователя на текущем шаге.
+	handle func(ctx context.Context, input *dialogInput) (string, string, error)
+}
+
+// dialogFlow описывает сценарий диалога
+type dialogFlow struct {
+	// start - первый шаг сценария
+	start string
+	steps map[string]*dialogStep
+}
+
+// dialogRetry - ошибка ввода: текст ошибки отправляется пользователю, шаг повторяется
+type dialogRetry string
+
+func (e dialogRetry) Error() string {
+	return string(e)
+}
+
+// startDialog начинает диалог flowName в чате пользователя, заменяя предыдущий, и задает первый вопрос
+func (s *ApplicationBot) startDialog(ctx context.Context, user *storage.User, flowName string) error {
+	flow, ok := s.dialogFlows[flowName]
+	if !ok {
+		return fmt.Errorf("unknown dialog flow %q", flowName)
+	}
+
+	data := map[string]string{}
+
+	if err := s.saveDialogState(ctx, user.ChatID, flowName, flow.start, data); err != nil {
+		return err
+	}
+
+	return s.sendDialogPrompt(user.ChatID, userLang(user), flow.steps[flow.start], data)
+}
+
+// handleText обрабатывает текстовые сообщения: передает ответ активному шагу диалога
+func (s *ApplicationBot) handleText(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	state, err := s.dialogs.GetDialogState(ctx, chatID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrDialogNotFound) {
+			log.Printf("Failed to get dialog state for %d: %v", chatID, err)
+		}
+		return nil
+	}
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", chatID, err)
+		s.finishDialog(ctx, chatID)
+		return c.Send(senderLang(c).T("error.not_registered"))
+	}
+
+	lang := userLang(user)
+
+	if time.Now().After(state.ExpiresAt) {
+		s.finishDialog(ctx, chatID)
+		return c.Send(lang.T("dialog.expired"), &tele.ReplyMarkup{RemoveKeyboard: true})
+	}
+
+	flow, ok := s.dialogFlows[state.Flow]
+	if !ok || flow.steps[state.Step] == nil {
+		log.Printf("Unknown dialog step %s/%s for %d", state.Flow, state.Step, chatID)
+		s.finishDialog(ctx, chatID)
+		return nil
+	}
+
+	data := map[string]string{}
+	if err := json.Unmarshal([]byte(state.Data), &data); err != nil {
+		log.Printf("Invalid dialog data for %d: %v", chatID, err)
+	}
+
+	input := &dialogInput{
+		User: user,
+		Lang: lang,
+		Text: c.Text(),
+		Data: data,
+	}
+
+	next, reply, err := flow.steps[state.Step].handle(ctx, input)
+	if err != nil {
+		var retry dialogRetry
+		if errors.As(err, &retry) {
+			// Продлеваем ожидание ответа на текущем шаге
+			state.ExpiresAt = time.Now().Add(dialogTimeout)
+			if err := s.dialogs.SaveDialogState(ctx, state); err != nil {
+				log.Printf("Failed to save dialog state for %d: %v", chatID, err)
+			}
+			return c.Send(retry.Error())
+		}
+
+		log.Printf("Failed to handle dialog step %s/%s for %d: %v", state.Flow, state.Step, chatID, err)
+		return c.Send(lang.T("dialog.error"))
+	}
+
+	if next == dialogEnd {
+		s.finishDialog(ctx, chatID)
+		return c.Send(reply, &tele.ReplyMarkup{RemoveKeyboard: true})
+	}
+
+	step, ok := flow.steps[next]
+	if !ok {
+		log.Printf("Unknown dialog step %s/%s for %d", state.Flow, next, chatID)
+		s.finishDialog(ctx, chatID)
+		return nil
+	}
+
+	if err := s.saveDialogState(ctx, chatID, state.Flow, next, input.Data); err != nil {
+		log.Printf("Failed to save dialog state for %d: %v", chatID, err)
+		return c.Send(lang.T("error.generic"))
+	}
+
+	if reply != "" {
+		if err := c.Send(reply); err != nil {
+			return err
+		}
+	}
+
+	return s.sendDialogPrompt(chatID, lang, step, input.Data)
+}
+
+// handleCancel обрабатывает команду /cancel
+func (s *ApplicationBot) handleCancel(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+	lang := userLang(s.getUserOrDefault(ctx, chatID, c.Sender().LanguageCode))
+
+	if _, err := s.dialogs.GetDialogState(ctx, chatID); err != nil {
+		if !errors.Is(err, storage.ErrDialogNotFound) {
+			log.Printf("Failed to get dialog state for %d: %v", chatID, err)
+		}
+		return c.Send(lang.T("dialog.nothing_to_cancel"), &tele.ReplyMarkup{RemoveKeyboard: true})
+	}
+
+	s.finishDialog(ctx, chatID)
+
+	return c.Send(lang.T("dialog.cancelled"), &tele.ReplyMarkup{RemoveKeyboard: true})
+}
+
+// PruneDialogs удаляет диалоги, время ожидания ответа в которых истекло
+func (s *ApplicationBot) PruneDialogs(ctx context.Context) error {
+	deleted, err := s.dialogs.DeleteExpiredDialogStates(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("Pruned %d expired dialogs", deleted)
+	}
+
+	return nil
+}
+
+// saveDialogState сохраняет шаг и данные диалога и продлевает время ожидания ответа
+func (s *ApplicationBot) saveDialogState(
+	ctx context.Context,
+	chatID int64,
+	flow, step string,
+	data map[string]string,
+) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode dialog data: %w", err)
+	}
+
+	return s.dialogs.SaveDialogState(
+		ctx, &storage.DialogState{
+			ChatID:    chatID,
+			Flow:      flow,
+			Step:      step,
+			Data:      string(encoded),
+			ExpiresAt: time.Now().Add(dialogTimeout),
+		},
+	)
+}
+
+// finishDialog завершает диалог в чате
+func (s *ApplicationBot) finishDialog(ctx context.Context, chatID int64) {
+	if err := s.dialogs.DeleteDialogState(ctx, chatID); err != nil {
+		log.Printf("Failed to delete dialog state for %d: %v", chatID, err)
+	}
+}
+
+// sendDialogPrompt задает вопрос шага; варианты ответа показываются кнопками клавиатуры
+func (s *ApplicationBot) sendDialogPrompt(
+	chatID int64,
+	lang i18n.Lang,
+	step *dialogStep,
+	data map[string]string,
+) error {
+	markup := &tele.ReplyMarkup{RemoveKeyboard: true}
+
+	if len(step.options) > 0 {
+		var row []tele.ReplyButton
+		for _, option := range step.options {
+			row = append(row, tele.ReplyButton{Text: lang.T(option)})
+		}
+
+		markup = &tele.ReplyMarkup{
+			ReplyKeyboard:   [][]tele.ReplyButton{row},
+			ResizeKeyboard:  true,
+			OneTimeKeyboard: true,
+		}
+	}
+
+	prompt := step.prompt(lang, data) + "\n\n" + lang.T("dialog.cancel_hint")
+
+	if _, err := s.bot.Send(&tele.Chat{ID: chatID}, prompt, markup); err != nil {
+		return fmt.Errorf("failed to send dialog prompt to %d: %w", chatID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,342 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+// Сценарии диалогов
+const (
+	dialogFlowLocation = "location"
+	dialogFlowTime     = "time"
+	dialogFlowPlace    = "place"
+
+	dialogFlowCommuteDeparture = "commute_departure"
+	dialogFlowCommuteReturn    = "commute_return"
+	dialogFlowTrip             = "trip"
+)
+
+// Ключи вариантов ответа в каталоге сообщений
+const (
+	dialogAnswerYes = "dialog.yes"
+	dialogAnswerNo  = "dialog.no"
+	// dialogAnswerKeepName - оставить найденное название места
+	dialogAnswerKeepName = "dialog.place.keep_name"
+)
+
+// registerDialogFlows регистрирует сценарии диалогов
+func (s *ApplicationBot) registerDialogFlows() {
+	s.dialogFlows = map[string]*dialogFlow{
+		dialogFlowLocation: {
+			start: "query",
+			steps: map[string]*dialogStep{
+				"query": {
+					prompt: func(lang i18n.Lang, _ map[string]string) string {
+						return lang.T("dialog.location.query")
+					},
+					handle: s.handleLocationQuery,
+				},
+				"confirm": {
+					prompt: func(lang i18n.Lang, data map[string]string) string {
+						return lang.T("dialog.location.confirm", data["name"], data["lat"], data["lon"])
+					},
+					options: []string{dialogAnswerYes, dialogAnswerNo},
+					handle:  s.handleLocationConfirm,
+				},
+			},
+		},
+		dialogFlowPlace: {
+			start: "query",
+			steps: map[string]*dialogStep{
+				"query": {
+					prompt: func(lang i18n.Lang, _ map[string]string) string {
+						return lang.T("dialog.location.query")
+					},
+					handle: s.handleLocationQuery,
+				},
+				"confirm": {
+					prompt: func(lang i18n.Lang, data map[string]string) string {
+						return lang.T("dialog.place.confirm", data["name"], data["lat"], data["lon"])
+					},
+					options: []string{dialogAnswerYes, dialogAnswerNo},
+					handle:  s.handlePlaceConfirm,
+				},
+				"name": {
+					prompt: func(lang i18n.Lang, data map[string]string) string {
+						return lang.T("dialog.place.name", data["name"])
+					},
+					options: []string{dialogAnswerKeepName},
+					handle:  s.handlePlaceName,
+				},
+			},
+		},
+		dialogFlowTime: {
+			start: "time",
+			steps: map[string]*dialogStep{
+				"time": {
+					prompt: func(lang i18n.Lang, _ map[string]string) string {
+						return lang.T("dialog.time.query", s.timezone)
+					},
+					handle: s.handleDeliveryTimeInput,
+				},
+			},
+		},
+		dialogFlowTrip: {
+			start: "query",
+			steps: map[string]*dialogStep{
+				"query": {
+					prompt: func(lang i18n.Lang, _ map[string]string) string {
+						return lang.T("dialog.trip.query")
+					},
+					handle: s.handleLocationQuery,
+				},
+				"confirm": {
+					prompt: func(lang i18n.Lang, data map[string]string) string {
+						return lang.T("dialog.trip.confirm", data["name"], data["lat"], data["lon"])
+					},
+					options: []string{dialogAnswerYes, dialogAnswerNo},
+					handle:  s.handleTripConfirm,
+				},
+				"dates": {
+					prompt: func(lang i18n.Lang, data map[string]string) string {
+						return lang.T("dialog.trip.dates", data["name"])
+					},
+					handle: s.handleTripDates,
+				},
+			},
+		},
+		dialogFlowCommuteDeparture: {
+			start: "time",
+			steps: map[string]*dialogStep{
+				"time": {
+					prompt: func(lang i18n.Lang, _ map[string]string) string {
+						return lang.T("dialog.commute.departure", s.timezone)
+					},
+					handle: s.handleCommuteDepartureInput,
+				},
+			},
+		},
+		dialogFlowCommuteReturn: {
+			start: "time",
+			steps: map[string]*dialogStep{
+				"time": {
+					prompt: func(lang i18n.Lang, _ map[string]string) string {
+						return lang.T("dialog.commute.return", s.timezone)
+					},
+					handle: s.handleCommuteReturnInput,
+				},
+			},
+		},
+	}
+}
+
+// handleLocationQuery ищет место по названию или координатам и переходит к подтверждению
+func (s *ApplicationBot) handleLocationQuery(ctx context.Context, input *dialogInput) (string, string, error) {
+	location, err := parseLocationArgs(strings.Fields(input.Text))
+	if err != nil {
+		return "", "", dialogRetry(input.Lang.T("dialog.location.invalid"))
+	}
+
+	weather, err := s.weatherService.GetWeather(ctx, location, input.Lang)
+	if err != nil {
+		if errors.Is(err, openweather.ErrLocationNotFound) {
+			return "", "", dialogRetry(input.Lang.T("dialog.location.not_found"))
+		}
+		return "", "", err
+	}
+
+	input.Data["name"] = placeName(weather)
+	input.Data["lat"] = strconv.FormatFloat(weather.Latitude, 'f', 4, 64)
+	input.Data["lon"] = strconv.FormatFloat(weather.Longitude, 'f', 4, 64)
+
+	// Смещение часового пояса нужно поездкам, чтобы присылать прогноз по местному времени
+	_, offset := time.Now().In(weather.Location).Zone()
+	input.Data["offset"] = strconv.Itoa(offset)
+
+	return "confirm", "", nil
+}
+
+// handleLocationConfirm сохраняет найденное место после подтверждения
+func (s *ApplicationBot) handleLocationConfirm(ctx context.Context, input *dialogInput) (string, string, error) {
+	switch input.Text {
+	case input.Lang.T(dialogAnswerYes):
+	case input.Lang.T(dialogAnswerNo):
+		return "query", "", nil
+	default:
+		return "", "", dialogRetry(
+			input.Lang.T("dialog.choose", input.Lang.T(dialogAnswerYes), input.Lang.T(dialogAnswerNo)),
+		)
+	}
+
+	latitude, longitude, err := parseCoordinates([]string{input.Data["lat"], input.Data["lon"]})
+	if err != nil {
+		return "", "", fmt.Errorf("invalid dialog coordinates: %w", err)
+	}
+
+	if err := s.storage.UpdateLocation(ctx, input.User.ChatID, input.Data["name"], latitude, longitude); err != nil {
+		return "", "", err
+	}
+
+	return dialogEnd, input.Lang.T("location.saved", input.Data["name"]), nil
+}
+
+// handlePlaceConfirm переходит к выбору названия найденного места после подтверждения
+func (s *ApplicationBot) handlePlaceConfirm(_ context.Context, input *dialogInput) (string, string, error) {
+	switch input.Text {
+	case input.Lang.T(dialogAnswerYes):
+		return "name", "", nil
+	case input.Lang.T(dialogAnswerNo):
+		return "query", "", nil
+	default:
+		return "", "", dialogRetry(
+			input.Lang.T("dialog.choose", input.Lang.T(dialogAnswerYes), input.Lang.T(dialogAnswerNo)),
+		)
+	}
+}
+
+// handlePlaceName сохраняет место под названием, которое ввел пользователь
+func (s *ApplicationBot) handlePlaceName(ctx context.Context, input *dialogInput) (string, string, error) {
+	name := strings.TrimSpace(input.Text)
+	if name == input.Lang.T(dialogAnswerKeepName) {
+		name = input.Data["name"]
+	}
+
+	if name == "" || utf8.RuneCountInString(name) > maxLocationNameLength {
+		return "", "", dialogRetry(input.Lang.T("dialog.place.name_invalid", maxLocationNameLength))
+	}
+
+	latitude, longitude, err := parseCoordinates([]string{input.Data["lat"], input.Data["lon"]})
+	if err != nil {
+		return "", "", fmt.Errorf("invalid dialog coordinates: %w", err)
+	}
+
+	notice, err := s.addUserLocation(ctx, input.User, name, latitude, longitude)
+	if err != nil {
+		return "", "", err
+	}
+
+	return dialogEnd, notice, nil
+}
+
+// handleTripConfirm переходит к вводу дат поездки после подтверждения места
+func (s *ApplicationBot) handleTripConfirm(_ context.Context, input *dialogInput) (string, string, error) {
+	switch input.Text {
+	case input.Lang.T(dialogAnswerYes):
+		return "dates", "", nil
+	case input.Lang.T(dialogAnswerNo):
+		return "query", "", nil
+	default:
+		return "", "", dialogRetry(
+			input.Lang.T("dialog.choose", input.Lang.T(dialogAnswerYes), input.Lang.T(dialogAnswerNo)),
+		)
+	}
+}
+
+// handleTripDates сохраняет поездку с введенными датами
+func (s *ApplicationBot) handleTripDates(ctx context.Context, input *dialogInput) (string, string, error) {
+	today := dateOf(s.now())
+
+	start, end, err := parseTripDates(input.Text, today)
+	if err != nil {
+		return "", "", dialogRetry(input.Lang.T("dialog.trip.dates_invalid"))
+	}
+	if end.Before(today) {
+		return "", "", dialogRetry(input.Lang.T("dialog.trip.dates_past"))
+	}
+	if end.Sub(start) >= maxTripDays*24*time.Hour {
+		return "", "", dialogRetry(input.Lang.T("dialog.trip.too_long", maxTripDays))
+	}
+
+	latitude, longitude, err := parseCoordinates([]string{input.Data["lat"], input.Data["lon"]})
+	if err != nil {
+		return "", "", fmt.Errorf("invalid dialog coordinates: %w", err)
+	}
+
+	offset, err := strconv.Atoi(input.Data["offset"])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid dialog timezone offset: %w", err)
+	}
+
+	trip := &storage.Trip{
+		ChatID:    input.User.ChatID,
+		Name:      input.Data["name"],
+		Latitude:  latitude,
+		Longitude: longitude,
+		UTCOffset: offset,
+		StartDate: start,
+		EndDate:   end,
+	}
+
+	if err := s.trips.SaveTrip(ctx, trip); err != nil {
+		return "", "", err
+	}
+
+	return dialogEnd, input.Lang.T(
+		"trip.saved",
+		trip.Name,
+		formatTripDates(input.Lang, trip),
+		formatUTCOffset(trip.UTCOffset),
+		s.weatherService.LocationForUser(input.User).Name,
+	), nil
+}
+
+// handleDeliveryTimeInput сохраняет время рассылки, введенное вручную
+func (s *ApplicationBot) handleDeliveryTimeInput(ctx context.Context, input *dialogInput) (string, string, error) {
+	minute, err := parseMinuteOfDay(input.Text)
+	if err != nil {
+		return "", "", dialogRetry(input.Lang.T("dialog.time.invalid"))
+	}
+
+	if err := s.storage.UpdateDeliveryTime(ctx, input.User.ChatID, &minute); err != nil {
+		return "", "", err
+	}
+
+	return dialogEnd, input.Lang.T("settings.time.set", formatMinuteOfDay(minute)), nil
+}
+
+// handleCommuteDepartureInput сохраняет время выезда из дома
+func (s *ApplicationBot) handleCommuteDepartureInput(ctx context.Context, input *dialogInput) (string, string, error) {
+	minute, err := parseMinuteOfDay(input.Text)
+	if err != nil {
+		return "", "", dialogRetry(input.Lang.T("dialog.time.invalid"))
+	}
+
+	if err := s.storage.UpdateCommuteTimes(ctx, input.User.ChatID, minute, input.User.CommuteReturn); err != nil {
+		return "", "", err
+	}
+
+	return dialogEnd, input.Lang.T("settings.commute.departure_set", formatMinuteOfDay(minute)), nil
+}
+
+// handleCommuteReturnInput сохраняет время возвращения с работы
+func (s *ApplicationBot) handleCommuteReturnInput(ctx context.Context, input *dialogInput) (string, string, error) {
+	minute, err := parseMinuteOfDay(input.Text)
+	if err != nil {
+		return "", "", dialogRetry(input.Lang.T("dialog.time.invalid"))
+	}
+
+	if err := s.storage.UpdateCommuteTimes(ctx, input.User.ChatID, input.User.CommuteDeparture, minute); err != nil {
+		return "", "", err
+	}
+
+	return dialogEnd, input.Lang.T("settings.commute.return_set", formatMinuteOfDay(minute)), nil
+}
+
+// parseMinuteOfDay разбирает время "ЧЧ:ММ" (или "ЧЧ.ММ") в минуты от начала суток
+func parseMinuteOfDay(text string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.Replace(strings.TrimSpace(text), ".", ":", 1))
+	if err != nil {
+		return 0, err
+	}
+
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
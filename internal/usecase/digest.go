@@ -0,0 +1,386 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+)
+
+// rainyForecastProbability - вероятность осадков, начиная с которой день в прогнозе считается дождливым
+const rainyForecastProbability = 0.5
+
+// DailySummary содержит сводку погоды за один день
+type DailySummary struct {
+	Date           time.Time
+	MinTemperature float64
+	MaxTemperature float64
+	Rain           bool
+	Snow           bool
+	// Precipitation - ожидаемое количество осадков, мм (только для прогноза)
+	Precipitation float64
+	// PrecipitationProbability - максимальная вероятность осадков от 0 до 1 (только для прогноза)
+	PrecipitationProbability float64
+}
+
+// WeeklyDigest содержит еженедельную сводку погоды
+type WeeklyDigest struct {
+	LocationName   string
+	PastDays       []DailySummary
+	ComingDays     []DailySummary
+	MinTemperature float64
+	MaxTemperature float64
+	RainyDays      int
+}
+
+// MonthlyDigest содержит итоги месяца
+type MonthlyDigest struct {
+	LocationName string
+	Month        time.Month
+	Year         int
+	Days         int
+	Warmest      *storage.WeatherObservation
+	Coldest      *storage.WeatherObservation
+	Windiest     *storage.WeatherObservation
+	RainyDays    int
+	SnowyDays    int
+	// Рекорды относительно всей предыдущей истории наблюдений
+	WarmestRecord  bool
+	ColdestRecord  bool
+	WindiestRecord bool
+}
+
+// GetForecast получает прогноз погоды для места с описаниями на языке lang. Прогноз кэшируется
+// на forecastCacheTTL, поэтому рассылка запрашивает его один раз для места, а не для каждого
+// пользователя. Возвращаемый срез общий для всех вызовов и не должен изменяться.
+func (s *WeatherService) GetForecast(ctx context.Context, location openweather.Location, lang i18n.Lang) (
+	[]openweather.ForecastEntry,
+	error,
+) {
+	key := forecastKey(location, lang)
+	if forecast, ok := s.forecasts.Get(key); ok {
+		return forecast, nil
+	}
+
+	forecast, err := s.client.GetForecast(ctx, location, string(lang))
+	if err != nil {
+		return nil, err
+	}
+
+	s.forecasts.Set(key, forecast)
+
+	return forecast, nil
+}
+
+// forecastKey возвращает ключ кэша прогноза для места и языка описаний
+func forecastKey(location openweather.Location, lang i18n.Lang) string {
+	if location.HasCoordinates() {
+		return observationKey(location.Latitude, location.Longitude) + ":" + string(lang)
+	}
+
+	return location.Name + "," + location.CountryCode + ":" + string(lang)
+}
+
+// BuildWeeklyDigest собирает сводку за прошедшие 7 дней и прогноз на ближайшие дни.
+// Границы дней считаются в часовом поясе now.
+func (s *WeatherService) BuildWeeklyDigest(ctx context.Context, location openweather.Location, now time.Time) (
+	*WeeklyDigest,
+	error,
+) {
+	location, err := s.resolveLocation(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve location: %w", err)
+	}
+
+	key := observationKey(location.Latitude, location.Longitude)
+	today := startOfDay(now)
+
+	observations, err := s.observations.GetObservations(ctx, key, today.AddDate(0, 0, -6), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observations: %w", err)
+	}
+
+	forecast, err := s.GetForecast(ctx, location, i18n.Default)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	digest := &WeeklyDigest{
+		LocationName: location.Name,
+		PastDays:     summarizeObservations(observations, now.Location()),
+		ComingDays:   summarizeForecast(forecast, today.AddDate(0, 0, 1)),
+	}
+
+	for i, day := range digest.PastDays {
+		if i == 0 || day.MinTemperature < digest.MinTemperature {
+			digest.MinTemperature = day.MinTemperature
+		}
+		if i == 0 || day.MaxTemperature > digest.MaxTemperature {
+			digest.MaxTemperature = day.MaxTemperature
+		}
+		if day.Rain {
+			digest.RainyDays++
+		}
+	}
+
+	return digest, nil
+}
+
+// BuildMonthlyDigest собирает итоги месяца, в котором находится now
+func (s *WeatherService) BuildMonthlyDigest(ctx context.Context, location openweather.Location, now time.Time) (
+	*MonthlyDigest,
+	error,
+) {
+	location, err := s.resolveLocation(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve location: %w", err)
+	}
+
+	key := observationKey(location.Latitude, location.Longitude)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	observations, err := s.observations.GetObservations(ctx, key, monthStart, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observations: %w", err)
+	}
+
+	history, err := s.observations.GetObservationExtremes(ctx, key, time.Unix(0, 0), monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observation extremes: %w", err)
+	}
+
+	digest := &MonthlyDigest{
+		LocationName: location.Name,
+		Month:        now.Month(),
+		Year:         now.Year(),
+	}
+
+	for _, observation := range observations {
+		observation.ObservedAt = observation.ObservedAt.In(now.Location())
+
+		if digest.Warmest == nil || observation.Temperature > digest.Warmest.Temperature {
+			digest.Warmest = observation
+		}
+		if digest.Coldest == nil || observation.Temperature < digest.Coldest.Temperature {
+			digest.Coldest = observation
+		}
+		if digest.Windiest == nil || observation.WindSpeed > digest.Windiest.WindSpeed {
+			digest.Windiest = observation
+		}
+	}
+
+	days := summarizeObservations(observations, now.Location())
+	digest.Days = len(days)
+	for _, day := range days {
+		if day.Rain {
+			digest.RainyDays++
+		}
+		if day.Snow {
+			digest.SnowyDays++
+		}
+	}
+
+	if history.Count > 0 && digest.Warmest != nil {
+		digest.WarmestRecord = digest.Warmest.Temperature > history.MaxTemperature
+		digest.ColdestRecord = digest.Coldest.Temperature < history.MinTemperature
+		digest.WindiestRecord = digest.Windiest.WindSpeed > history.MaxWindSpeed
+	}
+
+	return digest, nil
+}
+
+// FormatWeeklyDigest форматирует еженедельную сводку погоды
+func (s *WeatherService) FormatWeeklyDigest(lang i18n.Lang, u units.Preferences, digest *WeeklyDigest) string {
+	var b strings.Builder
+
+	b.WriteString(lang.T("digest.weekly.title", digest.LocationName) + "\n\n")
+
+	if len(digest.PastDays) > 0 {
+		b.WriteString(lang.T("digest.weekly.past") + "\n")
+		for _, day := range digest.PastDays {
+			fmt.Fprintf(&b, "%s: %s", formatDay(lang, day.Date), formatTemperatureRange(u, day))
+			if day.Rain {
+				b.WriteString(" ☔")
+			}
+			if day.Snow {
+				b.WriteString(" 🌨")
+			}
+			b.WriteString("\n")
+		}
+
+		b.WriteString(
+			"\n" + lang.T(
+				"digest.weekly.totals",
+				u.Temperature.FromCelsius(digest.MaxTemperature),
+				u.Temperature.FromCelsius(digest.MinTemperature),
+				digest.RainyDays,
+				len(digest.PastDays),
+			) + "\n\n",
+		)
+	} else {
+		b.WriteString(lang.T("digest.weekly.no_observations") + "\n\n")
+	}
+
+	if len(digest.ComingDays) > 0 {
+		b.WriteString(lang.T("digest.weekly.coming") + "\n")
+		for _, day := range digest.ComingDays {
+			b.WriteString(formatForecastDay(lang, u, day) + "\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatMonthlyDigest форматирует итоги месяца
+func (s *WeatherService) FormatMonthlyDigest(lang i18n.Lang, u units.Preferences, digest *MonthlyDigest) string {
+	var b strings.Builder
+
+	b.WriteString(
+		lang.T(
+			"digest.monthly.title",
+			digest.LocationName,
+			lang.T(fmt.Sprintf("month.%d", digest.Month)),
+			digest.Year,
+		) + "\n\n",
+	)
+
+	if digest.Warmest == nil {
+		b.WriteString(lang.T("digest.monthly.no_observations"))
+		return b.String()
+	}
+
+	lines := []string{
+		lang.T(
+			"digest.monthly.warmest",
+			u.Temperature.FromCelsius(digest.Warmest.Temperature),
+			digest.Warmest.ObservedAt.Format("02.01"),
+		),
+		lang.T(
+			"digest.monthly.coldest",
+			u.Temperature.FromCelsius(digest.Coldest.Temperature),
+			digest.Coldest.ObservedAt.Format("02.01"),
+		),
+		lang.T(
+			"digest.monthly.windiest",
+			formatSpeed(lang, u, digest.Windiest.WindSpeed),
+			digest.Windiest.ObservedAt.Format("02.01"),
+		),
+		lang.T("digest.monthly.days", digest.RainyDays, digest.SnowyDays, digest.Days),
+	}
+	b.WriteString(strings.Join(lines, "\n") + "\n")
+
+	var records []string
+	if digest.WarmestRecord {
+		records = append(records, lang.T("digest.monthly.record_warmest"))
+	}
+	if digest.ColdestRecord {
+		records = append(records, lang.T("digest.monthly.record_coldest"))
+	}
+	if digest.WindiestRecord {
+		records = append(records, lang.T("digest.monthly.record_windiest"))
+	}
+	if len(records) > 0 {
+		b.WriteString("\n" + lang.T("digest.monthly.records", strings.Join(records, ", ")))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// summarizeObservations группирует наблюдения по дням в часовом поясе loc
+func summarizeObservations(observations []*storage.WeatherObservation, loc *time.Location) []DailySummary {
+	var days []DailySummary
+
+	for _, observation := range observations {
+		date := startOfDay(observation.ObservedAt.In(loc))
+
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(
+				days, DailySummary{
+					Date:           date,
+					MinTemperature: observation.Temperature,
+					MaxTemperature: observation.Temperature,
+				},
+			)
+		}
+
+		day := &days[len(days)-1]
+		day.MinTemperature = math.Min(day.MinTemperature, observation.Temperature)
+		day.MaxTemperature = math.Max(day.MaxTemperature, observation.Temperature)
+		day.Rain = day.Rain || observation.Rain
+		day.Snow = day.Snow || observation.Snow
+	}
+
+	return days
+}
+
+// summarizeForecast группирует прогноз по дням, начиная с from, в часовом поясе from
+func summarizeForecast(entries []openweather.ForecastEntry, from time.Time) []DailySummary {
+	var days []DailySummary
+
+	for _, entry := range entries {
+		date := startOfDay(entry.Time.In(from.Location()))
+		if date.Before(from) {
+			continue
+		}
+
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(
+				days, DailySummary{
+					Date:           date,
+					MinTemperature: entry.TempMin,
+					MaxTemperature: entry.TempMax,
+				},
+			)
+		}
+
+		day := &days[len(days)-1]
+		day.MinTemperature = math.Min(day.MinTemperature, entry.TempMin)
+		day.MaxTemperature = math.Max(day.MaxTemperature, entry.TempMax)
+		day.Rain = day.Rain || entry.Rain > 0
+		day.Snow = day.Snow || entry.Snow > 0
+		day.Precipitation += entry.Rain + entry.Snow
+		day.PrecipitationProbability = math.Max(day.PrecipitationProbability, entry.PrecipitationProbability)
+	}
+
+	return days
+}
+
+// startOfDay возвращает начало дня для t в его часовом поясе
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// formatDay форматирует дату в виде "пн 20.10"
+func formatDay(lang i18n.Lang, date time.Time) string {
+	return fmt.Sprintf("%s %s", weekdayName(lang, date.Weekday()), date.Format("02.01"))
+}
+
+// weekdayName возвращает краткое название дня недели
+func weekdayName(lang i18n.Lang, weekday time.Weekday) string {
+	return lang.T(fmt.Sprintf("weekday.%d", weekday))
+}
+
+// formatForecastDay форматирует прогноз на день в виде "пн 20.10: +1…+6°, осадки 4.5 мм (80%)"
+func formatForecastDay(lang i18n.Lang, u units.Preferences, day DailySummary) string {
+	line := fmt.Sprintf("%s: %s", formatDay(lang, day.Date), formatTemperatureRange(u, day))
+	if day.PrecipitationProbability >= rainyForecastProbability {
+		line += lang.T("forecast.precipitation", day.Precipitation, day.PrecipitationProbability*100)
+	}
+
+	return line
+}
+
+// formatTemperatureRange форматирует диапазон температур дня в виде "-2…+5°"
+func formatTemperatureRange(u units.Preferences, day DailySummary) string {
+	return fmt.Sprintf(
+		"%+.0f…%+.0f°",
+		u.Temperature.FromCelsius(day.MinTemperature),
+		u.Temperature.FromCelsius(day.MaxTemperature),
+	)
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+)
+
+const (
+	// minTodayEntries - минимум интервалов прогноза на сегодня; если осталось меньше,
+	// показываются ближайшие сутки
+	minTodayEntries = 2
+	// dayEntries - количество трехчасовых интервалов в сутках
+	dayEntries = 8
+)
+
+// FormatTodayForecast форматирует прогноз на оставшуюся часть дня по трехчасовым интервалам
+func (s *WeatherService) FormatTodayForecast(
+	lang i18n.Lang,
+	u units.Preferences,
+	locationName string,
+	entries []openweather.ForecastEntry,
+) string {
+	if len(entries) == 0 {
+		return lang.T("forecast.empty", locationName)
+	}
+
+	todayEntries, today := dayForecast(entries)
+
+	title := lang.T("forecast.today", locationName)
+	if !today {
+		title = lang.T("forecast.next_day", locationName)
+	}
+
+	lines := []string{title}
+	for _, entry := range todayEntries {
+		line := fmt.Sprintf(
+			"%s %s %+.0f°, %s",
+			entry.Time.Format("15:04"),
+			entry.Condition.Emoji(entry.Night),
+			u.Temperature.FromCelsius(entry.Temperature),
+			entry.Description,
+		)
+		if entry.PrecipitationProbability >= rainyForecastProbability {
+			line += fmt.Sprintf(" ☔ %.0f%%", entry.PrecipitationProbability*100)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// FormatDaysForecast форматирует прогноз по дням на days дней, начиная с завтрашнего
+func (s *WeatherService) FormatDaysForecast(
+	lang i18n.Lang,
+	u units.Preferences,
+	locationName string,
+	entries []openweather.ForecastEntry,
+	days int,
+) string {
+	if len(entries) == 0 {
+		return lang.T("forecast.empty", locationName)
+	}
+
+	tomorrow := startOfDay(time.Now().In(entries[0].Time.Location())).AddDate(0, 0, 1)
+	summaries := summarizeForecast(entries, tomorrow)
+	summaries = summaries[:min(days, len(summaries))]
+
+	lines := []string{
+		lang.T("forecast.days", len(summaries), lang.Plural("plural.days", len(summaries)), locationName),
+	}
+	for _, day := range summaries {
+		lines = append(lines, formatForecastDay(lang, u, day))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -2,7 +2,15 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
 
 	tele "gopkg.in/telebot.v3"
 )
@@ -15,143 +23,306 @@ func (s *ApplicationBot) handleStart(c tele.Context) error {
 	// Создаем или обновляем пользователя
 	if err := s.storage.CreateUser(ctx, chatID); err != nil {
 		log.Printf("Failed to create user %d: %v", chatID, err)
-		return c.Send("Произошла ошибка при регистрации. Попробуйте позже.")
+		return c.Send(senderLang(c).T("error.registration"))
+	}
+
+	if err := s.storage.UpdateLanguageCode(ctx, chatID, c.Sender().LanguageCode); err != nil {
+		log.Printf("Failed to update language code for user %d: %v", chatID, err)
 	}
 
-	welcomeMsg := "👋 Добро пожаловать в DeepCake Bot!\n\n" +
-		"Я буду отправлять вам прогноз погоды каждое утро в 07:00 по МСК.\n\n" +
-		"Доступные команды:\n" +
-		"/settings - настройки рассылки"
+	user := s.getUserOrDefault(ctx, chatID, c.Sender().LanguageCode)
+	lang := userLang(user)
+
+	// Пользователь, который отписался или блокировал бота, возвращается с прежними настройками
+	if !user.Active {
+		if err := s.storage.ActivateUser(ctx, chatID); err != nil {
+			log.Printf("Failed to activate user %d: %v", chatID, err)
+			return c.Send(lang.T("error.registration"))
+		}
+
+		return c.Send(lang.T("start.welcome_back", formatMinuteOfDay(s.deliveryTime(user))))
+	}
 
-	return c.Send(welcomeMsg)
+	return c.Send(lang.T("start.welcome", formatMinuteOfDay(s.defaultDeliveryTime)))
 }
 
-func (s *ApplicationBot) handleGetWeather(c tele.Context) error {
+// handleStop обрабатывает команду /stop: отписывает пользователя от всех рассылок
+func (s *ApplicationBot) handleStop(c tele.Context) error {
 	ctx := context.Background()
 	chatID := c.Chat().ID
 
-	err := s.SendWeatherToUser(ctx, chatID)
+	user, err := s.storage.GetUser(ctx, chatID)
 	if err != nil {
-		return err
+		return c.Send(senderLang(c).T("error.not_registered"))
 	}
+	lang := userLang(user)
 
-	return nil
-}
-
-// Кнопки для настроек
-var (
-	btnEnableWeather = tele.InlineButton{
-		Unique: "enable_weather",
-		Text:   "✅ Включить рассылку",
+	if !user.Active {
+		return c.Send(lang.T("stop.already"))
 	}
-	btnDisableWeather = tele.InlineButton{
-		Unique: "disable_weather",
-		Text:   "❌ Выключить рассылку",
+
+	if err := s.storage.DeactivateUser(ctx, chatID, storage.InactiveReasonStopped); err != nil {
+		log.Printf("Failed to deactivate user %d: %v", chatID, err)
+		return c.Send(lang.T("error.generic"))
 	}
-)
 
-// handleSettings обрабатывает команду /settings
-func (s *ApplicationBot) handleSettings(c tele.Context) error {
+	return c.Send(lang.T("stop.done"))
+}
+
+// handleGetWeather обрабатывает команду /weather.
+// Без аргументов показывает погоду в сохраненном городе, с аргументами -
+// в городе ("/weather Kazan") или по координатам ("/weather 55.75 37.61").
+func (s *ApplicationBot) handleGetWeather(c tele.Context) error {
 	ctx := context.Background()
 	chatID := c.Chat().ID
 
-	// Получаем текущие настройки пользователя
-	user, err := s.storage.GetUser(ctx, chatID)
-	if err != nil {
-		log.Printf("Failed to get user %d: %v", chatID, err)
-		return c.Send("Сначала отправьте команду /start для регистрации.")
+	user := s.getUserOrDefault(ctx, chatID, c.Sender().LanguageCode)
+	lang := userLang(user)
+
+	if len(c.Args()) == 0 {
+		return s.sendLocationsWeather(c, user)
 	}
 
-	var statusText string
-	var keyboard *tele.ReplyMarkup
+	location, err := parseLocationArgs(c.Args())
+	if err != nil {
+		return c.Send(lang.T("weather.usage"))
+	}
 
-	if user.WeatherEnabled {
-		statusText = "✅ Утренняя рассылка погоды *включена*\n\nВы будете получать прогноз каждый день в 07:00 МСК."
-		keyboard = &tele.ReplyMarkup{
-			InlineKeyboard: [][]tele.InlineButton{
-				{btnDisableWeather},
-			},
-		}
-	} else {
-		statusText = "❌ Утренняя рассылка погоды *выключена*\n\nВы не будете получать ежедневные прогнозы."
-		keyboard = &tele.ReplyMarkup{
-			InlineKeyboard: [][]tele.InlineButton{
-				{btnEnableWeather},
-			},
+	report, err := s.BuildWeatherReport(ctx, user, location)
+	if err != nil {
+		if errors.Is(err, openweather.ErrLocationNotFound) {
+			return c.Send(lang.T("error.location_not_found"))
 		}
+		log.Printf("Failed to get weather for %q: %v", c.Message().Payload, err)
+		return c.Send(lang.T("error.weather"))
+	}
+
+	coordinates := formatCoordinates(report.Weather.Latitude, report.Weather.Longitude)
+
+	saveButton := btnSaveLocation
+	saveButton.Text = lang.T("button.save_location")
+	saveButton.Data = coordinates
+
+	addButton := btnAddLocation
+	addButton.Text = lang.T("button.add_location")
+	addButton.Data = coordinates
+
+	keyboard := &tele.ReplyMarkup{
+		InlineKeyboard: [][]tele.InlineButton{
+			{saveButton},
+			{addButton},
+		},
+	}
+
+	message, err := s.weatherService.FormatWeatherMessage(lang, unitsForUser(user), report)
+	if err != nil {
+		log.Printf("Failed to format weather message: %v", err)
+		return c.Send(lang.T("error.generic"))
 	}
 
-	return c.Send(statusText, keyboard, tele.ModeMarkdown)
+	return c.Send(message, keyboard)
 }
 
-// handleEnableWeather обрабатывает нажатие кнопки включения рассылки
-func (s *ApplicationBot) handleEnableWeather(c tele.Context) error {
+// handleSaveLocation обрабатывает нажатие кнопки сохранения города из /weather
+func (s *ApplicationBot) handleSaveLocation(c tele.Context) error {
 	ctx := context.Background()
 	chatID := c.Chat().ID
+	lang := userLang(s.getUserOrDefault(ctx, chatID, c.Sender().LanguageCode))
 
-	if err := s.storage.UpdateWeatherEnabled(ctx, chatID, true); err != nil {
-		log.Printf("Failed to enable weather for user %d: %v", chatID, err)
+	latitude, longitude, err := parseCoordinates(strings.Split(c.Data(), "|"))
+	if err != nil {
+		log.Printf("Invalid save location data %q: %v", c.Data(), err)
 		return c.Respond(
 			&tele.CallbackResponse{
-				Text: "Произошла ошибка. Попробуйте позже.",
+				Text: lang.T("error.generic"),
 			},
 		)
 	}
 
-	// Обновляем сообщение
-	keyboard := &tele.ReplyMarkup{
-		InlineKeyboard: [][]tele.InlineButton{
-			{btnDisableWeather},
+	weather, err := s.weatherService.GetWeather(
+		ctx, openweather.Location{
+			Latitude:  latitude,
+			Longitude: longitude,
 		},
+		lang,
+	)
+	if err != nil {
+		log.Printf("Failed to get weather for saved location: %v", err)
+		return c.Respond(
+			&tele.CallbackResponse{
+				Text: lang.T("error.generic"),
+			},
+		)
 	}
 
-	if err := c.Edit(
-		"✅ Утренняя рассылка погоды *включена*\n\nВы будете получать прогноз каждый день в 07:00 МСК.",
-		keyboard,
-		tele.ModeMarkdown,
-	); err != nil {
+	name := placeName(weather)
+
+	if err := s.storage.CreateUser(ctx, chatID); err != nil {
+		log.Printf("Failed to create user %d: %v", chatID, err)
+		return c.Respond(
+			&tele.CallbackResponse{
+				Text: lang.T("error.generic"),
+			},
+		)
+	}
+
+	if err := s.storage.UpdateLocation(ctx, chatID, name, latitude, longitude); err != nil {
+		log.Printf("Failed to save location for user %d: %v", chatID, err)
+		return c.Respond(
+			&tele.CallbackResponse{
+				Text: lang.T("error.generic"),
+			},
+		)
+	}
+
+	// Убираем кнопку сохранения под сообщением
+	if _, err := c.Bot().EditReplyMarkup(c.Message(), nil); err != nil {
 		log.Printf("Failed to edit message: %v", err)
 	}
 
 	return c.Respond(
 		&tele.CallbackResponse{
-			Text: "Рассылка включена!",
+			Text: lang.T("location.saved", name),
 		},
 	)
 }
 
-// handleDisableWeather обрабатывает нажатие кнопки выключения рассылки
-func (s *ApplicationBot) handleDisableWeather(c tele.Context) error {
+// handleChart обрабатывает команду /chart
+func (s *ApplicationBot) handleChart(c tele.Context) error {
 	ctx := context.Background()
 	chatID := c.Chat().ID
 
-	if err := s.storage.UpdateWeatherEnabled(ctx, chatID, false); err != nil {
-		log.Printf("Failed to disable weather for user %d: %v", chatID, err)
-		return c.Respond(
-			&tele.CallbackResponse{
-				Text: "Произошла ошибка. Попробуйте позже.",
-			},
-		)
-	}
+	user := s.getUserOrDefault(ctx, chatID, c.Sender().LanguageCode)
 
-	// Обновляем сообщение
-	keyboard := &tele.ReplyMarkup{
-		InlineKeyboard: [][]tele.InlineButton{
-			{btnEnableWeather},
-		},
+	if err := s.SendChartToUser(ctx, user, s.weatherService.LocationForUser(user)); err != nil {
+		log.Printf("Failed to send chart to user %d: %v", chatID, err)
+		return c.Send(userLang(user).T("error.chart"))
 	}
 
-	if err := c.Edit(
-		"❌ Утренняя рассылка погоды *выключена*\n\nВы не будете получать ежедневные прогнозы.",
-		keyboard,
-		tele.ModeMarkdown,
-	); err != nil {
-		log.Printf("Failed to edit message: %v", err)
+	return nil
+}
+
+// handleSun обрабатывает команду /sun
+func (s *ApplicationBot) handleSun(c tele.Context) error {
+	ctx := context.Background()
+
+	user := s.getUserOrDefault(ctx, c.Chat().ID, c.Sender().LanguageCode)
+	lang := userLang(user)
+	location := s.weatherService.LocationForUser(user)
+
+	weather, err := s.weatherService.GetWeather(ctx, location, lang)
+	if err != nil {
+		log.Printf("Failed to get weather for /sun: %v", err)
+		return c.Send(lang.T("error.data"))
 	}
 
-	return c.Respond(
-		&tele.CallbackResponse{
-			Text: "Рассылка выключена.",
-		},
+	message, err := s.weatherService.FormatSunMessage(
+		lang,
+		s.weatherService.LocationName(location, weather),
+		s.weatherService.GetAstro(weather),
 	)
+	if err != nil {
+		log.Printf("Failed to format /sun message: %v", err)
+		return c.Send(lang.T("error.data"))
+	}
+
+	return c.Send(message)
+}
+
+// getUserOrDefault получает пользователя или, если он не зарегистрирован,
+// возвращает пользователя с настройками по умолчанию и языком Telegram languageCode
+func (s *ApplicationBot) getUserOrDefault(ctx context.Context, chatID int64, languageCode string) *storage.User {
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		user = defaultUser(chatID)
+		user.LanguageCode = languageCode
+	}
+
+	return user
+}
+
+// senderLang возвращает язык Telegram отправителя; используется, пока пользователь не найден
+func senderLang(c tele.Context) i18n.Lang {
+	if c.Sender() == nil {
+		return i18n.Default
+	}
+
+	return i18n.FromTelegram(c.Sender().LanguageCode)
 }
+
+// defaultUser возвращает незарегистрированного пользователя с настройками по умолчанию
+func defaultUser(chatID int64) *storage.User {
+	return &storage.User{
+		ChatID:         chatID,
+		Active:         true,
+		WeatherEnabled: true,
+		DeliveryDays:   storage.AllDeliveryDays,
+		AlertRules:     storage.AllAlertRules,
+	}
+}
+
+// parseLocationArgs разбирает аргументы команды: координаты "широта долгота", "широта,долгота"
+// или "широта, долгота" либо название города
+func parseLocationArgs(args []string) (openweather.Location, error) {
+	coordinates := args
+	switch {
+	case len(args) == 1 && strings.Count(args[0], ",") == 1:
+		coordinates = strings.Split(args[0], ",")
+	case len(args) == 2 && strings.HasSuffix(args[0], ","):
+		coordinates = []string{strings.TrimSuffix(args[0], ","), args[1]}
+	}
+
+	if latitude, longitude, err := parseCoordinates(coordinates); err == nil {
+		return openweather.Location{
+			Latitude:  latitude,
+			Longitude: longitude,
+		}, nil
+	}
+
+	name := strings.TrimSpace(strings.Join(args, " "))
+	if name == "" {
+		return openweather.Location{}, errors.New("empty location")
+	}
+
+	return openweather.Location{Name: name}, nil
+}
+
+// parseCoordinates разбирает пару "широта долгота" и проверяет диапазоны
+func parseCoordinates(parts []string) (float64, float64, error) {
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected 2 coordinates, got %d", len(parts))
+	}
+
+	latitude, err := strconv.ParseFloat(strings.Replace(parts[0], ",", ".", 1), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid latitude: %w", err)
+	}
+
+	longitude, err := strconv.ParseFloat(strings.Replace(parts[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid longitude: %w", err)
+	}
+
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return 0, 0, fmt.Errorf("coordinates out of range: %f, %f", latitude, longitude)
+	}
+
+	return latitude, longitude, nil
+}
+
+// placeName возвращает название места из ответа погоды или, если его нет, координаты
+func placeName(weather *openweather.WeatherData) string {
+	if weather.City != "" {
+		return weather.City
+	}
+
+	return fmt.Sprintf("%.2f, %.2f", weather.Latitude, weather.Longitude)
+}
+
+// formatCoordinates форматирует координаты для данных кнопки
+func formatCoordinates(latitude, longitude float64) string {
+	return fmt.Sprintf("%.4f|%.4f", latitude, longitude)
+}
+
+// btnSaveLocation - кнопка сохранения города из /weather, в данных - координаты
+var btnSaveLocation = tele.InlineButton{Unique: "save_location"}
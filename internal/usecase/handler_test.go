@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+)
+
+func TestParseLocationArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want openweather.Location
+	}{
+		{
+			name: "coordinates separated by space",
+			args: []string{"55.75", "37.61"},
+			want: openweather.Location{Latitude: 55.75, Longitude: 37.61},
+		},
+		{
+			name: "coordinates with decimal commas",
+			args: []string{"55,75", "37,61"},
+			want: openweather.Location{Latitude: 55.75, Longitude: 37.61},
+		},
+		{
+			name: "coordinates separated by comma",
+			args: []string{"55.75,37.61"},
+			want: openweather.Location{Latitude: 55.75, Longitude: 37.61},
+		},
+		{
+			name: "coordinates separated by comma and space",
+			args: []string{"55.75,", "37.61"},
+			want: openweather.Location{Latitude: 55.75, Longitude: 37.61},
+		},
+		{
+			name: "negative coordinates separated by comma",
+			args: []string{"-33.87,151.21"},
+			want: openweather.Location{Latitude: -33.87, Longitude: 151.21},
+		},
+		{
+			name: "city name",
+			args: []string{"Nizhny", "Novgorod"},
+			want: openweather.Location{Name: "Nizhny Novgorod"},
+		},
+		{
+			name: "city name with country",
+			args: []string{"Paris,FR"},
+			want: openweather.Location{Name: "Paris,FR"},
+		},
+		{
+			name: "coordinates out of range",
+			args: []string{"95,37"},
+			want: openweather.Location{Name: "95,37"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := parseLocationArgs(tt.args)
+				if err != nil {
+					t.Fatalf("parseLocationArgs(%q) error = %v", tt.args, err)
+				}
+
+				if got != tt.want {
+					t.Errorf("parseLocationArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestParseLocationArgsEmpty(t *testing.T) {
+	if _, err := parseLocationArgs(nil); err == nil {
+		t.Error("parseLocationArgs(nil) error = nil, want error")
+	}
+}
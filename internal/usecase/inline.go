@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// inlineCacheTTL - время жизни результатов inline-запроса
+	inlineCacheTTL = 10 * time.Minute
+	// minInlineQueryLength - минимальная длина запроса, чтобы не искать по первым буквам
+	minInlineQueryLength = 2
+	// inlineForecastDays - количество дней в сводке прогноза
+	inlineForecastDays = 3
+)
+
+// inlineAnswer содержит подготовленные тексты результатов inline-запроса
+type inlineAnswer struct {
+	// NotFound - место не найдено, результатов нет
+	NotFound     bool
+	LocationName string
+	Summary      string
+	Current      string
+	Today        string
+	Days         string
+}
+
+// handleInlineQuery обрабатывает inline-запросы вида "@DeepCakeBot Sochi".
+// Пустой запрос показывает погоду в сохраненном городе пользователя.
+func (s *ApplicationBot) handleInlineQuery(c tele.Context) error {
+	ctx := context.Background()
+	query := strings.TrimSpace(c.Query().Text)
+
+	user := s.getUserOrDefault(ctx, c.Sender().ID, c.Sender().LanguageCode)
+	lang := userLang(user)
+	u := unitsForUser(user)
+
+	var location openweather.Location
+	var key string
+
+	if query == "" {
+		location = s.weatherService.LocationForUser(user)
+		key = strings.ToLower(location.Name)
+	} else {
+		if len([]rune(query)) < minInlineQueryLength {
+			return c.Answer(&tele.QueryResponse{})
+		}
+
+		var err error
+		location, err = parseLocationArgs(strings.Fields(query))
+		if err != nil {
+			return c.Answer(&tele.QueryResponse{})
+		}
+		key = strings.ToLower(query)
+	}
+
+	// Тексты результатов зависят от языка и единиц измерения, поэтому они входят в ключ кэша
+	key = fmt.Sprintf("%s:%s:%s:%s:%s", lang, u.Temperature, u.Speed, u.Pressure, key)
+
+	answer, ok := s.inlineCache.Get(key)
+	if !ok {
+		var err error
+		answer, err = s.buildInlineAnswer(ctx, location, lang, u)
+		if err != nil {
+			log.Printf("Failed to build inline answer for %q: %v", query, err)
+			return c.Answer(&tele.QueryResponse{})
+		}
+
+		s.inlineCache.Set(key, answer)
+	}
+
+	// Ответ зависит от города, языка и единиц пользователя, поэтому Telegram кэширует его
+	// только для этого пользователя
+	if answer.NotFound {
+		return c.Answer(&tele.QueryResponse{CacheTime: int(inlineCacheTTL.Seconds()), IsPersonal: true})
+	}
+
+	results := tele.Results{
+		inlineArticle("current", lang.T("inline.current", answer.LocationName), answer.Summary, answer.Current),
+		inlineArticle("today", lang.T("inline.today", answer.LocationName), "", answer.Today),
+		inlineArticle(
+			"days",
+			lang.T(
+				"inline.days",
+				inlineForecastDays,
+				lang.Plural("plural.days", inlineForecastDays),
+				answer.LocationName,
+			),
+			"",
+			answer.Days,
+		),
+	}
+
+	return c.Answer(
+		&tele.QueryResponse{
+			Results:    results,
+			CacheTime:  int(inlineCacheTTL.Seconds()),
+			IsPersonal: true,
+		},
+	)
+}
+
+// buildInlineAnswer получает погоду и прогноз для места и готовит тексты результатов
+func (s *ApplicationBot) buildInlineAnswer(
+	ctx context.Context,
+	location openweather.Location,
+	lang i18n.Lang,
+	u units.Preferences,
+) (*inlineAnswer, error) {
+	// Результаты кэшируются для всех пользователей, поэтому собираются с настройками по умолчанию
+	user := defaultUser(0)
+	user.Language = string(lang)
+
+	report, err := s.BuildWeatherReport(ctx, user, location)
+	if err != nil {
+		if errors.Is(err, openweather.ErrLocationNotFound) {
+			return &inlineAnswer{NotFound: true}, nil
+		}
+		return nil, err
+	}
+
+	forecast, err := s.weatherService.GetForecast(ctx, location, lang)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	current, err := s.weatherService.FormatWeatherMessage(lang, u, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inlineAnswer{
+		LocationName: report.LocationName,
+		Summary: fmt.Sprintf(
+			"%+.0f°, %s",
+			u.Temperature.FromCelsius(report.Weather.Temperature),
+			report.Weather.Description,
+		),
+		Current: current,
+		Today:   s.weatherService.FormatTodayForecast(lang, u, report.LocationName, forecast),
+		Days:    s.weatherService.FormatDaysForecast(lang, u, report.LocationName, forecast, inlineForecastDays),
+	}, nil
+}
+
+// inlineArticle создает текстовый результат inline-запроса
+func inlineArticle(id, title, description, text string) *tele.ArticleResult {
+	return &tele.ArticleResult{
+		ResultBase: tele.ResultBase{
+			ID: id,
+			Content: &tele.InputTextMessageContent{
+				Text: text,
+			},
+		},
+		Title:       title,
+		Description: description,
+	}
+}
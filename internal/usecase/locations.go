@@ -0,0 +1,405 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// maxUserLocations - сколько мест может сохранить пользователь
+	maxUserLocations = 10
+	// maxLocationNameLength - максимальная длина названия места в символах
+	maxLocationNameLength = 32
+	// mainLocationID - ID основного города в данных кнопок переключения мест
+	mainLocationID = 0
+	// locationButtonsPerRow - кнопок переключения мест в одном ряду
+	locationButtonsPerRow = 2
+
+	// locationsSeparator разделяет места в общем утреннем сообщении
+	locationsSeparator = "\n\n———\n\n"
+	// maxMessageLength - максимальная длина сообщения в Telegram
+	maxMessageLength = 4096
+	// morningMessageLength - максимальная длина сообщения утреннего прогноза: в первом сообщении
+	// остается место для пометки об опоздании
+	morningMessageLength = maxMessageLength - 256
+)
+
+// Кнопки под сообщением /weather
+var (
+	btnWeatherLocation = tele.InlineButton{Unique: "weather_location"}
+	btnAddLocation     = tele.InlineButton{Unique: "add_location"}
+)
+
+// userLocation возвращает место для запроса погоды по сохраненному месту пользователя
+func userLocation(location *storage.UserLocation) openweather.Location {
+	return openweather.Location{
+		Name:      location.Name,
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+	}
+}
+
+// buildLocationReport собирает сообщение о погоде для места с ID id:
+// mainLocationID - основной город, иначе сохраненное место пользователя
+func (s *ApplicationBot) buildLocationReport(ctx context.Context, user *storage.User, id uint) (
+	*WeatherReport,
+	error,
+) {
+	if id == mainLocationID {
+		report, err := s.BuildWeatherReport(ctx, user, s.weatherService.LocationForUser(user))
+		if err != nil {
+			return nil, err
+		}
+
+		// Прогноз на дорогу относится к пользователю, а не к месту, поэтому показывается
+		// только вместе с основным городом
+		if user.CommuteEnabled && slices.Contains(report.Sections, SectionCommute) {
+			report.Commute, err = s.BuildCommuteForecast(ctx, user)
+			if err != nil {
+				log.Printf("Failed to build commute forecast for user %d: %v", user.ChatID, err)
+			}
+		}
+
+		return report, nil
+	}
+
+	location, err := s.locations.GetUserLocation(ctx, user.ChatID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.BuildWeatherReport(ctx, user, userLocation(location))
+	if err != nil {
+		return nil, err
+	}
+
+	// Сохраненные места показываются под названием, которое дал пользователь
+	report.LocationName = location.Name
+
+	return report, nil
+}
+
+// buildMorningMessages собирает утреннее сообщение по основному городу и сохраненным местам,
+// отмеченным для утренней рассылки. Если сообщение длиннее лимита Telegram,
+// оно делится на несколько по границам мест, а слишком длинное место - по строкам.
+func (s *ApplicationBot) buildMorningMessages(ctx context.Context, user *storage.User) ([]string, error) {
+	ids := []uint{mainLocationID}
+
+	locations, err := s.locations.GetUserLocations(ctx, user.ChatID)
+	if err != nil {
+		log.Printf("Failed to get locations of user %d: %v", user.ChatID, err)
+	}
+
+	for _, location := range locations {
+		if location.InMorning {
+			ids = append(ids, location.ID)
+		}
+	}
+
+	lang := userLang(user)
+	u := unitsForUser(user)
+
+	var parts []string
+
+	for _, id := range ids {
+		report, err := s.buildLocationReport(ctx, user, id)
+		if err != nil {
+			// Основной город обязателен, остальные места пропускаются при ошибке
+			if id == mainLocationID {
+				return nil, err
+			}
+			log.Printf("Failed to build weather report for location %d of user %d: %v", id, user.ChatID, err)
+			continue
+		}
+
+		part, err := s.weatherService.FormatWeatherMessage(lang, u, report)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, part)
+	}
+
+	return splitMessage(parts, locationsSeparator, morningMessageLength), nil
+}
+
+// splitMessage объединяет части через separator в сообщения не длиннее limit символов.
+// Часть, которая сама длиннее лимита, делится на несколько сообщений по строкам.
+func splitMessage(parts []string, separator string, limit int) []string {
+	var messages []string
+	var current string
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) > limit {
+			if current != "" {
+				messages = append(messages, current)
+				current = ""
+			}
+			messages = append(messages, splitLongMessage(part, limit)...)
+			continue
+		}
+
+		if current == "" {
+			current = part
+			continue
+		}
+
+		if utf8.RuneCountInString(current)+utf8.RuneCountInString(separator)+utf8.RuneCountInString(part) > limit {
+			messages = append(messages, current)
+			current = part
+			continue
+		}
+
+		current += separator + part
+	}
+
+	if current != "" {
+		messages = append(messages, current)
+	}
+
+	return messages
+}
+
+// splitLongMessage делит текст на сообщения не длиннее limit символов по границам строк;
+// строка длиннее лимита делится посимвольно
+func splitLongMessage(text string, limit int) []string {
+	var messages []string
+	var current []rune
+
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+
+		if len(current) > 0 && len(current)+1+len(runes) > limit {
+			messages = append(messages, string(current))
+			current = nil
+		}
+
+		if len(current) > 0 {
+			current = append(current, '\n')
+		}
+
+		for len(current)+len(runes) > limit {
+			n := limit - len(current)
+			messages = append(messages, string(append(current, runes[:n]...)))
+			current, runes = nil, runes[n:]
+		}
+
+		current = append(current, runes...)
+	}
+
+	if len(current) > 0 {
+		messages = append(messages, string(current))
+	}
+
+	return messages
+}
+
+// weatherLocationsKeyboard формирует кнопки переключения между основным городом
+// и сохраненными местами; текущее место отмечено
+func (s *ApplicationBot) weatherLocationsKeyboard(
+	user *storage.User,
+	locations []storage.UserLocation,
+	current uint,
+) [][]tele.InlineButton {
+	if len(locations) == 0 {
+		return nil
+	}
+
+	button := func(name string, id uint) tele.InlineButton {
+		if id == current {
+			name = "• " + name
+		}
+
+		b := btnWeatherLocation
+		b.Text = name
+		b.Data = strconv.FormatUint(uint64(id), 10)
+
+		return b
+	}
+
+	buttons := []tele.InlineButton{button(s.weatherService.LocationForUser(user).Name, mainLocationID)}
+	for _, location := range locations {
+		buttons = append(buttons, button(location.Name, location.ID))
+	}
+
+	var keyboard [][]tele.InlineButton
+	for len(buttons) > 0 {
+		n := min(locationButtonsPerRow, len(buttons))
+		keyboard = append(keyboard, buttons[:n])
+		buttons = buttons[n:]
+	}
+
+	return keyboard
+}
+
+// sendLocationsWeather отправляет погоду в основном городе с кнопками переключения между местами
+func (s *ApplicationBot) sendLocationsWeather(c tele.Context, user *storage.User) error {
+	ctx := context.Background()
+	lang := userLang(user)
+
+	report, err := s.buildLocationReport(ctx, user, mainLocationID)
+	if err != nil {
+		log.Printf("Failed to get weather for user %d: %v", user.ChatID, err)
+		return c.Send(lang.T("error.weather"))
+	}
+
+	message, err := s.weatherService.FormatWeatherMessage(lang, unitsForUser(user), report)
+	if err != nil {
+		log.Printf("Failed to format weather message: %v", err)
+		return c.Send(lang.T("error.generic"))
+	}
+
+	locations, err := s.locations.GetUserLocations(ctx, user.ChatID)
+	if err != nil {
+		log.Printf("Failed to get locations of user %d: %v", user.ChatID, err)
+	}
+
+	keyboard := s.weatherLocationsKeyboard(user, locations, mainLocationID)
+	if keyboard == nil {
+		return c.Send(message)
+	}
+
+	return c.Send(message, &tele.ReplyMarkup{InlineKeyboard: keyboard})
+}
+
+// handleWeatherLocation обрабатывает переключение места под сообщением /weather
+func (s *ApplicationBot) handleWeatherLocation(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: senderLang(c).T("error.not_registered")})
+	}
+	lang := userLang(user)
+
+	id, err := strconv.ParseUint(c.Data(), 10, 0)
+	if err != nil {
+		log.Printf("Invalid weather location data %q: %v", c.Data(), err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	report, err := s.buildLocationReport(ctx, user, uint(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserLocationNotFound) {
+			return c.Respond(&tele.CallbackResponse{Text: lang.T("location.deleted")})
+		}
+		log.Printf("Failed to get weather for location %d of user %d: %v", id, chatID, err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.weather")})
+	}
+
+	message, err := s.weatherService.FormatWeatherMessage(lang, unitsForUser(user), report)
+	if err != nil {
+		log.Printf("Failed to format weather message: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	locations, err := s.locations.GetUserLocations(ctx, chatID)
+	if err != nil {
+		log.Printf("Failed to get locations of user %d: %v", chatID, err)
+	}
+
+	keyboard := s.weatherLocationsKeyboard(user, locations, uint(id))
+
+	if err := c.Edit(message, &tele.ReplyMarkup{InlineKeyboard: keyboard}); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+
+	return c.Respond()
+}
+
+// handleAddLocation обрабатывает кнопку добавления места из /weather в сохраненные места
+func (s *ApplicationBot) handleAddLocation(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: senderLang(c).T("error.not_registered")})
+	}
+	lang := userLang(user)
+
+	latitude, longitude, err := parseCoordinates(strings.Split(c.Data(), "|"))
+	if err != nil {
+		log.Printf("Invalid add location data %q: %v", c.Data(), err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	weather, err := s.weatherService.GetWeather(
+		ctx, openweather.Location{
+			Latitude:  latitude,
+			Longitude: longitude,
+		},
+		lang,
+	)
+	if err != nil {
+		log.Printf("Failed to get weather for added location: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	name := placeName(weather)
+
+	notice, err := s.addUserLocation(ctx, user, name, latitude, longitude)
+	if err != nil {
+		log.Printf("Failed to add location for user %d: %v", chatID, err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: notice})
+}
+
+// addUserLocation сохраняет место пользователя, если не превышен лимит мест.
+// Возвращает текст уведомления для пользователя.
+func (s *ApplicationBot) addUserLocation(
+	ctx context.Context,
+	user *storage.User,
+	name string,
+	latitude, longitude float64,
+) (string, error) {
+	lang := userLang(user)
+
+	locations, err := s.locations.GetUserLocations(ctx, user.ChatID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(locations) >= maxUserLocations {
+		return lang.T("location.limit", maxUserLocations), nil
+	}
+
+	err = s.locations.AddUserLocation(
+		ctx, &storage.UserLocation{
+			ChatID:    user.ChatID,
+			Name:      name,
+			Latitude:  latitude,
+			Longitude: longitude,
+			InMorning: true,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return lang.T("location.added", name), nil
+}
+
+// parseLocationID разбирает ID сохраненного места из аргумента кнопки
+func parseLocationID(arg string) (uint, error) {
+	id, err := strconv.ParseUint(arg, 10, 0)
+	if err != nil || id == mainLocationID {
+		return 0, fmt.Errorf("invalid location id %q", arg)
+	}
+
+	return uint(id), nil
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "parts fit into one message",
+			parts: []string{"Moscow", "Home"},
+			limit: 20,
+			want:  []string{"Moscow | Home"},
+		},
+		{
+			name:  "parts split at part boundaries",
+			parts: []string{"Moscow", "Home", "Office"},
+			limit: 13,
+			want:  []string{"Moscow | Home", "Office"},
+		},
+		{
+			name:  "long part split by lines",
+			parts: []string{"Moscow", "line one\nline two\nline three"},
+			limit: 18,
+			want:  []string{"Moscow", "line one\nline two", "line three"},
+		},
+		{
+			name:  "long line split by characters",
+			parts: []string{"абвгдежзий"},
+			limit: 4,
+			want:  []string{"абвг", "дежз", "ий"},
+		},
+		{
+			name:  "long line after short line",
+			parts: []string{"ab\ncdefgh"},
+			limit: 4,
+			want:  []string{"ab", "cdef", "gh"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := splitMessage(tt.parts, " | ", tt.limit)
+
+				if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+					t.Errorf("splitMessage() = %q, want %q", got, tt.want)
+				}
+
+				for _, message := range got {
+					if utf8.RuneCountInString(message) > tt.limit {
+						t.Errorf("message %q is longer than %d", message, tt.limit)
+					}
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Варианты паузы рассылки в данных кнопки btnPause
+const (
+	pauseTomorrow = "tomorrow"
+	pauseWeek     = "week"
+)
+
+// Кнопки под утренним сообщением
+var (
+	btnPause  = tele.InlineButton{Unique: "pause"}
+	btnResume = tele.InlineButton{Unique: "resume"}
+)
+
+// pauseKeyboard формирует кнопки паузы под утренним сообщением
+func pauseKeyboard(lang i18n.Lang) *tele.ReplyMarkup {
+	week := btnPause
+	week.Text = lang.T("button.pause_week")
+	week.Data = pauseWeek
+
+	tomorrow := btnPause
+	tomorrow.Text = lang.T("button.pause_tomorrow")
+	tomorrow.Data = pauseTomorrow
+
+	return &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{week, tomorrow}}}
+}
+
+// resumeKeyboard формирует кнопку возобновления рассылки вместо кнопок паузы
+func resumeKeyboard(lang i18n.Lang) *tele.ReplyMarkup {
+	resume := btnResume
+	resume.Text = lang.T("button.resume")
+
+	return &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{resume}}}
+}
+
+// pauseSlotGrace - сколько пауза "завтра" длится после пропускаемой рассылки
+const pauseSlotGrace = time.Minute
+
+// pausedUntil возвращает время окончания паузы option, начатой в now, и текст ответа:
+// "завтра" пропускает ближайшую рассылку пользователя, "неделя" - следующие 7 дней
+func (s *ApplicationBot) pausedUntil(
+	ctx context.Context,
+	user *storage.User,
+	option string,
+	now time.Time,
+) (time.Time, string, bool) {
+	lang := userLang(user)
+
+	switch option {
+	case pauseTomorrow:
+		slot, ok := s.nextDeliverySlot(ctx, user, now)
+		if !ok {
+			until := startOfDay(now).AddDate(0, 0, 2)
+			return until, lang.T("pause.set", formatDay(lang, until)), true
+		}
+
+		return slot.Add(pauseSlotGrace), lang.T("pause.skipped", formatDay(lang, slot)), true
+	case pauseWeek:
+		until := startOfDay(now).AddDate(0, 0, 8)
+		return until, lang.T("pause.set", formatDay(lang, until)), true
+	default:
+		return time.Time{}, "", false
+	}
+}
+
+// nextDeliverySlot возвращает ближайшее после now время утренней рассылки пользователя с учетом
+// дней рассылки. Во время поездки рассылка идет по местному времени места поездки.
+func (s *ApplicationBot) nextDeliverySlot(ctx context.Context, user *storage.User, now time.Time) (time.Time, bool) {
+	trip, err := s.trips.GetTrip(ctx, user.ChatID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrTripNotFound) {
+			log.Printf("Failed to get trip of user %d: %v", user.ChatID, err)
+		}
+		trip = nil
+	}
+
+	zones := []*time.Location{now.Location()}
+	if trip != nil {
+		zones = append(zones, tripZone(trip))
+	}
+
+	minute := time.Duration(s.deliveryTime(user)) * time.Minute
+
+	var next time.Time
+	for i, zone := range zones {
+		// Дни рассылки повторяются каждую неделю, поэтому ближайшая рассылка в поясе не дальше недели
+		for days := 0; days <= 7; days++ {
+			slot := startOfDay(now.In(zone)).AddDate(0, 0, days).Add(minute)
+			if !slot.After(now) || user.DeliveryDays&(1<<slot.Weekday()) == 0 {
+				continue
+			}
+
+			// Во время поездки рассылка идет только по времени поездки, а вне ее - только по времени дома
+			if (trip != nil && tripActive(trip, slot)) != (i > 0) {
+				continue
+			}
+
+			if next.IsZero() || slot.Before(next) {
+				next = slot
+			}
+			break
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// isPaused сообщает, приостановлена ли рассылка пользователя в момент now
+func isPaused(user *storage.User, now time.Time) bool {
+	return user.PausedUntil != nil && user.PausedUntil.After(now)
+}
+
+// handlePause обрабатывает кнопки паузы под утренним сообщением
+func (s *ApplicationBot) handlePause(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: senderLang(c).T("error.not_registered")})
+	}
+	lang := userLang(user)
+
+	until, text, ok := s.pausedUntil(ctx, user, c.Data(), s.now())
+	if !ok {
+		log.Printf("Invalid pause data %q", c.Data())
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	if err := s.storage.UpdatePausedUntil(ctx, chatID, &until); err != nil {
+		log.Printf("Failed to pause weather for user %d: %v", chatID, err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	if err := c.Edit(resumeKeyboard(lang)); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: text})
+}
+
+// handleResume обрабатывает кнопку возобновления рассылки
+func (s *ApplicationBot) handleResume(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: senderLang(c).T("error.not_registered")})
+	}
+	lang := userLang(user)
+
+	if err := s.storage.UpdatePausedUntil(ctx, chatID, nil); err != nil {
+		log.Printf("Failed to resume weather for user %d: %v", chatID, err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	if err := c.Edit(pauseKeyboard(lang)); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: lang.T("pause.resumed")})
+}
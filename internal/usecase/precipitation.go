@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+)
+
+// forecastStep - длительность интервала прогноза
+const forecastStep = 3 * time.Hour
+
+// PrecipitationWindow - период с ожидаемыми осадками по прогнозу
+type PrecipitationWindow struct {
+	// Snow - ожидается снег, иначе дождь
+	Snow  bool
+	Start time.Time
+	End   time.Time
+	// Probability - наибольшая вероятность осадков за период, от 0 до 1
+	Probability float64
+	// Amount - ожидаемое количество осадков за период, мм
+	Amount float64
+}
+
+// GetPrecipitationWindows возвращает периоды с осадками на оставшуюся часть дня
+// (или на ближайшие сутки, если день заканчивается) в месте, для которого получена погода
+func (s *WeatherService) GetPrecipitationWindows(ctx context.Context, weather *openweather.WeatherData) (
+	[]PrecipitationWindow,
+	error,
+) {
+	location := openweather.Location{Latitude: weather.Latitude, Longitude: weather.Longitude}
+
+	forecast, err := s.GetForecast(ctx, location, i18n.Default)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	entries, _ := dayForecast(forecast)
+
+	return precipitationWindows(entries), nil
+}
+
+// dayForecast возвращает интервалы прогноза на оставшуюся часть дня, а если их
+// меньше minTodayEntries - на ближайшие сутки. today сообщает, какой вариант выбран.
+func dayForecast(entries []openweather.ForecastEntry) (day []openweather.ForecastEntry, today bool) {
+	if len(entries) == 0 {
+		return nil, true
+	}
+
+	start := startOfDay(time.Now().In(entries[0].Time.Location()))
+
+	for _, entry := range entries {
+		if startOfDay(entry.Time).Equal(start) {
+			day = append(day, entry)
+		}
+	}
+
+	if len(day) < minTodayEntries {
+		return entries[:min(dayEntries, len(entries))], false
+	}
+
+	return day, true
+}
+
+// precipitationWindows объединяет идущие подряд интервалы с вероятными осадками одного вида
+func precipitationWindows(entries []openweather.ForecastEntry) []PrecipitationWindow {
+	var windows []PrecipitationWindow
+
+	for _, entry := range entries {
+		amount := entry.Rain + entry.Snow
+		if entry.PrecipitationProbability < rainyForecastProbability ||
+			(amount == 0 && !entry.Condition.IsRain() && !entry.Condition.IsSnow()) {
+			continue
+		}
+
+		// Осадки интервала выпадают за forecastStep до его времени
+		snow := entry.Snow > entry.Rain || (amount == 0 && entry.Condition.IsSnow())
+		start := entry.Time.Add(-forecastStep)
+
+		if n := len(windows); n > 0 && windows[n-1].Snow == snow && windows[n-1].End.Equal(start) {
+			last := &windows[n-1]
+			last.End = entry.Time
+			last.Probability = max(last.Probability, entry.PrecipitationProbability)
+			last.Amount += amount
+
+			continue
+		}
+
+		windows = append(
+			windows, PrecipitationWindow{
+				Snow:        snow,
+				Start:       start,
+				End:         entry.Time,
+				Probability: entry.PrecipitationProbability,
+				Amount:      amount,
+			},
+		)
+	}
+
+	return windows
+}
+
+// formatHour форматирует время начала или конца периода: "14" или "14:30"
+func formatHour(t time.Time) string {
+	if t.Minute() == 0 {
+		return t.Format("15")
+	}
+
+	return t.Format("15:04")
+}
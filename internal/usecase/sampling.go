@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/queue"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+const (
+	// jobObservationsSample - сохранение наблюдений для мест, погоду в которых смотрят пользователи
+	jobObservationsSample = "observations.sample"
+
+	// sampleInterval - как часто сохраняются наблюдения для мест пользователей
+	sampleInterval = time.Hour
+)
+
+// observationsSamplePayload - параметры задачи jobObservationsSample
+type observationsSamplePayload struct {
+	At time.Time `json:"at"`
+}
+
+// registerSamplingJobs регистрирует обработчик задачи сохранения наблюдений
+func (s *Scheduler) registerSamplingJobs() {
+	// Цепочка задач не должна обрываться, поэтому ее задачи повторяются без ограничения
+	s.queue.Register(jobObservationsSample, queue.Options{}, s.handleObservationsSample)
+}
+
+// enqueueObservationsSample добавляет задачу сохранения наблюдений на время at.
+// Ключ задачи зависит только от времени, поэтому задача добавляется один раз.
+func (s *Scheduler) enqueueObservationsSample(ctx context.Context, at time.Time) error {
+	job, err := queue.NewJob(
+		jobObservationsSample,
+		fmt.Sprintf("%s:%d", jobObservationsSample, at.Unix()),
+		at,
+		observationsSamplePayload{At: at},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.queue.Enqueue(ctx, job)
+}
+
+// handleObservationsSample добавляет задачу на следующий интервал и сохраняет наблюдения.
+// Если бот не работал, пропущенные интервалы не наверстываются.
+func (s *Scheduler) handleObservationsSample(ctx context.Context, job *storage.Job) error {
+	var payload observationsSamplePayload
+	if err := queue.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	next := payload.At.Add(sampleInterval)
+	if current := time.Now().Truncate(sampleInterval); next.Before(current) {
+		next = current
+	}
+
+	if err := s.enqueueObservationsSample(ctx, next); err != nil {
+		return err
+	}
+
+	if err := s.weatherService.SampleObservations(ctx); err != nil {
+		return err
+	}
+
+	return s.refreshTripZones(ctx, time.Now())
+}
+
+// refreshTripZones обновляет смещение часового пояса мест поездок, которые идут или скоро начнутся.
+// Провайдер сообщает только текущее смещение, поэтому после перехода на летнее или зимнее время
+// прогноз для поездки приходит по новому местному времени не позже чем через sampleInterval.
+func (s *Scheduler) refreshTripZones(ctx context.Context, now time.Time) error {
+	today := dateOf(now)
+
+	trips, err := s.trips.GetTripsBetween(ctx, today.AddDate(0, 0, -1), today.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	for i := range trips {
+		trip := &trips[i]
+
+		weather, err := s.weatherService.GetWeather(ctx, tripLocation(trip), i18n.Default)
+		if err != nil {
+			log.Printf("Failed to get weather for trip of user %d: %v", trip.ChatID, err)
+			continue
+		}
+
+		_, offset := now.In(weather.Location).Zone()
+		if offset == trip.UTCOffset {
+			continue
+		}
+
+		if err := s.trips.UpdateTripOffset(ctx, trip.ID, offset); err != nil {
+			log.Printf("Failed to update trip offset of user %d: %v", trip.ChatID, err)
+			continue
+		}
+
+		log.Printf(
+			"Trip of user %d moved from %s to %s",
+			trip.ChatID, formatUTCOffset(trip.UTCOffset), formatUTCOffset(offset),
+		)
+	}
+
+	return nil
+}
@@ -2,124 +2,319 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"slices"
 	"time"
 
+	"github.com/qrave1/DeepCakeBot/internal/queue"
 	"github.com/qrave1/DeepCakeBot/internal/storage"
 )
 
 // Scheduler управляет планированием задач
 type Scheduler struct {
 	storage        storage.UserRepository
+	trips          storage.TripRepository
+	deliveries     storage.DeliveryRepository
+	queue          *queue.Queue
 	applicationBot *ApplicationBot
+	weatherService *WeatherService
 	timezone       *time.Location
 	scheduleHour   int
-	stopChan       chan struct{}
+	digestHour     int
+	// retryWindow - сколько времени после слота повторяется неудачная отправка прогноза
+	retryWindow time.Duration
+	// catchUpWindow - за сколько времени до запуска отправляются прогнозы, пропущенные, пока бот не работал
+	catchUpWindow time.Duration
+	// broadcastLimit - общий для всех реплик лимит скорости задач рассылки
+	broadcastLimit *queue.RateLimit
+	// weeklyDigests, monthlyDigests - сводки, собранные для мест за день
+	weeklyDigests  *ttlCache[*WeeklyDigest]
+	monthlyDigests *ttlCache[*MonthlyDigest]
 }
 
 // NewScheduler создает новый планировщик
-func NewScheduler(storage storage.UserRepository, applicationBot *ApplicationBot, timezoneName string, scheduleHour int) (
-	*Scheduler,
-	error,
-) {
+func NewScheduler(
+	storage storage.UserRepository,
+	trips storage.TripRepository,
+	deliveries storage.DeliveryRepository,
+	jobQueue *queue.Queue,
+	applicationBot *ApplicationBot,
+	weatherService *WeatherService,
+	timezoneName string,
+	scheduleHour int,
+	digestHour int,
+	retryWindow time.Duration,
+	catchUpWindow time.Duration,
+	broadcastRate float64,
+) (*Scheduler, error) {
 	location, err := time.LoadLocation(timezoneName)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Scheduler{
+	s := &Scheduler{
 		storage:        storage,
+		trips:          trips,
+		deliveries:     deliveries,
+		queue:          jobQueue,
 		applicationBot: applicationBot,
+		weatherService: weatherService,
 		timezone:       location,
 		scheduleHour:   scheduleHour,
-		stopChan:       make(chan struct{}),
-	}, nil
+		digestHour:     digestHour,
+		retryWindow:    retryWindow,
+		catchUpWindow:  catchUpWindow,
+		broadcastLimit: &queue.RateLimit{Name: broadcastRateLimit, Rate: broadcastRate},
+		weeklyDigests:  newTTLCache[*WeeklyDigest](digestCacheTTL),
+		monthlyDigests: newTTLCache[*MonthlyDigest](digestCacheTTL),
+	}
+
+	s.registerDeliveryJobs()
+	s.registerDailyJobs()
+	s.registerSamplingJobs()
+
+	return s, nil
 }
 
 // Start запускает планировщик
 func (s *Scheduler) Start(ctx context.Context) {
-	log.Printf("Scheduler started. Weather will be sent daily at %02d:00 %s", s.scheduleHour, s.timezone.String())
+	log.Printf(
+		"Scheduler started. Weather will be sent at users' delivery times (default %02d:00 %s)",
+		s.scheduleHour,
+		s.timezone.String(),
+	)
+	log.Printf("Digests will be sent on Sundays and month ends at %02d:00 %s", s.digestHour, s.timezone.String())
+	log.Printf("Trips will be checked daily at %02d:00 %s", s.digestHour, s.timezone.String())
 
-	// Запускаем первую проверку
-	go s.run(ctx)
-}
+	// Запускаем цепочку планирования рассылки с начала окна догоняющей рассылки: слоты окна сверяются
+	// с журналом, поэтому пропущенные прогнозы отправляются, даже если журнал пуст (первый запуск)
+	// или задача цепочки потеряна. Если цепочка уже есть в очереди, обе задачи добавят задачу
+	// для одного и того же следующего слота и цепочки сольются.
+	from := time.Now().In(s.timezone).Truncate(time.Minute).Add(-s.catchUpWindow)
+	if err := s.enqueueWeatherSchedule(ctx, from); err != nil {
+		log.Printf("Failed to start weather schedule: %v", err)
+	}
+
+	// Ежедневные задачи тоже образуют цепочку; задачи за сегодня добавляются, если их еще нет
+	if err := s.enqueueDailyJobs(ctx, s.today()); err != nil {
+		log.Printf("Failed to start daily jobs: %v", err)
+	}
 
-// Stop останавливает планировщик
-func (s *Scheduler) Stop() {
-	close(s.stopChan)
-	log.Println("Scheduler stopped")
+	if err := s.enqueueObservationsSample(ctx, time.Now().Truncate(sampleInterval)); err != nil {
+		log.Printf("Failed to start weather sampling: %v", err)
+	}
 }
 
-// run основной цикл планировщика
-func (s *Scheduler) run(ctx context.Context) {
-	nextRun := s.getNextRunTime()
-	log.Printf("Next weather broadcast scheduled at: %s", nextRun.Format("2006-01-02 15:04:05 MST"))
-
-	timer := time.NewTimer(time.Until(nextRun))
-	defer timer.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Scheduler context cancelled")
-			return
-		case <-s.stopChan:
-			log.Println("Scheduler stop signal received")
-			return
-		case <-timer.C:
-			log.Println("Starting scheduled weather broadcast...")
-			s.sendWeatherToAllUsers(ctx)
-
-			// Планируем следующий запуск (через 24 часа)
-			nextRun = s.getNextRunTime()
-			log.Printf("Next weather broadcast scheduled at: %s", nextRun.Format("2006-01-02 15:04:05 MST"))
-			timer.Reset(time.Until(nextRun))
+// scheduleWeatherForSlot записывает в журнал рассылки прогнозы пользователям, выбравшим время и день slot,
+// и добавляет в очередь задачи их отправки. Повторный вызов для того же слота не создает дублей:
+// пользователи, отправка которым по журналу уже завершена, пропускаются, а остальным недостающие
+// записи и задачи добавляются, поэтому слот можно обработать заново после любого сбоя.
+func (s *Scheduler) scheduleWeatherForSlot(ctx context.Context, slot time.Time) error {
+	minute := slot.Hour()*60 + slot.Minute()
+
+	users, err := s.storage.GetUsersForDelivery(ctx, minute, minute == s.scheduleHour*60, slot.Weekday(), slot)
+	if err != nil {
+		return fmt.Errorf("failed to get users for delivery at %s: %w", slot.Format("15:04"), err)
+	}
+
+	trips := s.activeTrips(ctx, slot)
+
+	// Во время поездки прогноз приходит по местному времени места поездки, а не по времени бота
+	var deliveries []storage.Delivery
+	for _, user := range users {
+		if _, ok := trips[user.ChatID]; !ok {
+			deliveries = append(deliveries, newDelivery(user.ChatID, slot, 0))
 		}
 	}
-}
+	deliveries = append(deliveries, s.tripDeliveries(ctx, slot, trips)...)
 
-// getNextRunTime вычисляет время следующего запуска
-func (s *Scheduler) getNextRunTime() time.Time {
-	now := time.Now().In(s.timezone)
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	finished, err := s.deliveries.GetFinishedDeliveryChats(ctx, slot)
+	if err != nil {
+		return err
+	}
 
-	next := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		s.scheduleHour, 0, 0, 0,
-		s.timezone,
+	done := make(map[int64]bool, len(finished))
+	for _, chatID := range finished {
+		done[chatID] = true
+	}
+
+	deliveries = slices.DeleteFunc(
+		deliveries, func(delivery storage.Delivery) bool {
+			return done[delivery.ChatID]
+		},
 	)
 
-	if now.After(next) || now.Equal(next) {
-		next = next.Add(24 * time.Hour)
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := s.deliveries.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
 	}
 
-	return next
+	jobs := make([]storage.Job, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		job, err := queue.NewJob(
+			jobWeatherDelivery,
+			fmt.Sprintf("%s:%d:%d", jobWeatherDelivery, delivery.ChatID, slot.Unix()),
+			slot,
+			weatherDeliveryPayload{ChatID: delivery.ChatID, Slot: slot},
+		)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := s.queue.Enqueue(ctx, jobs...); err != nil {
+		return err
+	}
+
+	log.Printf("Scheduled weather for %d users at %s", len(deliveries), slot.Format("15:04"))
+
+	return nil
 }
 
-// sendWeatherToAllUsers отправляет прогноз погоды всем пользователям с включенной рассылкой
-func (s *Scheduler) sendWeatherToAllUsers(ctx context.Context) {
-	users, err := s.storage.GetAllEnabledUsers(ctx)
+// activeTrips возвращает поездки, которые идут в момент slot по местному времени мест поездок
+func (s *Scheduler) activeTrips(ctx context.Context, slot time.Time) map[int64]*storage.Trip {
+	// Местная дата места поездки может отличаться от даты бота не больше чем на день
+	date := dateOf(slot)
+
+	trips, err := s.trips.GetTripsBetween(ctx, date.AddDate(0, 0, -1), date.AddDate(0, 0, 1))
 	if err != nil {
-		log.Printf("Failed to get enabled users: %v", err)
-		return
+		log.Printf("Failed to get trips at %s: %v", slot.Format("15:04"), err)
+		return nil
+	}
+
+	active := make(map[int64]*storage.Trip, len(trips))
+	for i := range trips {
+		if tripActive(&trips[i], slot) {
+			active[trips[i].ChatID] = &trips[i]
+		}
+	}
+
+	return active
+}
+
+// tripDeliveries выбирает пользователей в поездке, у которых в slot по местному времени
+// наступило время рассылки
+func (s *Scheduler) tripDeliveries(
+	ctx context.Context,
+	slot time.Time,
+	trips map[int64]*storage.Trip,
+) []storage.Delivery {
+	if len(trips) == 0 {
+		return nil
+	}
+
+	chatIDs := make([]int64, 0, len(trips))
+	for chatID := range trips {
+		chatIDs = append(chatIDs, chatID)
 	}
 
-	log.Printf("Sending weather to %d users...", len(users))
+	users, err := s.storage.GetUsers(ctx, chatIDs)
+	if err != nil {
+		log.Printf("Failed to get users for trip deliveries at %s: %v", slot.Format("15:04"), err)
+		return nil
+	}
 
-	successCount := 0
-	failCount := 0
+	var deliveries []storage.Delivery
 
 	for _, user := range users {
-		if err := s.applicationBot.SendWeatherToUser(ctx, user.ChatID); err != nil {
-			log.Printf("Failed to send weather to user %d: %v", user.ChatID, err)
-			failCount++
-		} else {
-			successCount++
+		trip := trips[user.ChatID]
+		local := slot.In(tripZone(trip))
+
+		if !user.Active || !user.WeatherEnabled || isPaused(user, slot) ||
+			s.applicationBot.deliveryTime(user) != local.Hour()*60+local.Minute() ||
+			user.DeliveryDays&(1<<local.Weekday()) == 0 {
+			continue
 		}
 
-		// Небольшая задержка между отправками, чтобы не превысить лимиты Telegram API
-		time.Sleep(50 * time.Millisecond)
+		deliveries = append(deliveries, newDelivery(user.ChatID, slot, trip.ID))
 	}
 
-	log.Printf("Weather broadcast completed. Success: %d, Failed: %d", successCount, failCount)
+	return deliveries
 }
 
+// processTrips отправляет прогноз для сборов перед поездками и завершает закончившиеся поездки.
+// Настройки дома во время поездки не меняются, поэтому после ее удаления прогноз снова приходит для дома.
+// Прогноз для сборов отправляется один раз, поэтому повторный запуск за тот же день безопасен.
+func (s *Scheduler) processTrips(ctx context.Context, _ time.Time) error {
+	now := time.Now().In(s.timezone)
+	today := dateOf(now)
+
+	var errs []error
+
+	trips, err := s.trips.GetTripsForPacking(ctx, today, today.AddDate(0, 0, tripPackingDays))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get trips for packing: %w", err))
+	}
+
+	for i := range trips {
+		trip := &trips[i]
+
+		user, err := s.storage.GetUser(ctx, trip.ChatID)
+		if err != nil {
+			log.Printf("Failed to get user %d for packing forecast: %v", trip.ChatID, err)
+			continue
+		}
+
+		if !user.Active {
+			continue
+		}
+
+		if err := s.applicationBot.SendPackingForecastToUser(ctx, user, trip); err != nil {
+			log.Printf("Failed to send packing forecast to user %d: %v", trip.ChatID, err)
+			s.applicationBot.DeactivateIfUnreachable(ctx, user.ChatID, err)
+			continue
+		}
+
+		if err := s.trips.MarkTripPackingSent(ctx, trip.ID); err != nil {
+			log.Printf("Failed to mark packing forecast sent for user %d: %v", trip.ChatID, err)
+		}
+	}
+
+	ended, err := s.trips.GetTripsEndedBefore(ctx, today)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to get ended trips: %w", err))
+		return errors.Join(errs...)
+	}
+
+	for i := range ended {
+		trip := &ended[i]
+
+		// По местному времени места поездки последний день может еще идти
+		if !tripDate(trip, now).After(trip.EndDate) {
+			continue
+		}
+
+		if err := s.trips.DeleteTrip(ctx, trip.ChatID); err != nil {
+			log.Printf("Failed to delete ended trip of user %d: %v", trip.ChatID, err)
+			continue
+		}
+
+		user, err := s.storage.GetUser(ctx, trip.ChatID)
+		if err != nil {
+			log.Printf("Failed to get user %d for ended trip: %v", trip.ChatID, err)
+			continue
+		}
+
+		if !user.Active {
+			continue
+		}
+
+		message := userLang(user).T("trip.ended", trip.Name, s.weatherService.LocationForUser(user).Name)
+		if err := s.applicationBot.SendTextToUser(ctx, user.ChatID, message); err != nil {
+			log.Printf("Failed to send trip end notice to user %d: %v", user.ChatID, err)
+			s.applicationBot.DeactivateIfUnreachable(ctx, user.ChatID, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
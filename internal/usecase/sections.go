@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+)
+
+// Section - раздел утреннего сообщения; имя раздела совпадает с блоком шаблона
+type Section string
+
+const (
+	SectionSummary    Section = "summary"
+	SectionDetails    Section = "details"
+	SectionClothing   Section = "clothing"
+	SectionAir        Section = "air"
+	SectionAstro      Section = "astro"
+	SectionAlerts     Section = "alerts"
+	SectionComparison Section = "comparison"
+	SectionCommute    Section = "commute"
+)
+
+// sectionsSeparator разделяет разделы в настройках пользователя
+const sectionsSeparator = ","
+
+// allSections - все разделы в порядке по умолчанию
+var allSections = []Section{
+	SectionSummary,
+	SectionDetails,
+	SectionCommute,
+	SectionAlerts,
+	SectionComparison,
+	SectionClothing,
+	SectionAir,
+	SectionAstro,
+}
+
+// defaultSections - разделы сообщения, пока пользователь их не настроил
+var defaultSections = []Section{
+	SectionSummary,
+	SectionDetails,
+	SectionCommute,
+	SectionAlerts,
+	SectionComparison,
+	SectionClothing,
+}
+
+// sectionsForUser возвращает включенные разделы пользователя в порядке вывода
+func sectionsForUser(user *storage.User) []Section {
+	if user.Sections == "" {
+		sections := slices.Clone(defaultSections)
+		if user.AstroEnabled {
+			sections = append(sections, SectionAstro)
+		}
+
+		return sections
+	}
+
+	if sections := parseSections(user.Sections); len(sections) > 0 {
+		return sections
+	}
+
+	return slices.Clone(defaultSections)
+}
+
+// parseSections разбирает список разделов, пропуская неизвестные и повторяющиеся
+func parseSections(value string) []Section {
+	var sections []Section
+
+	for _, name := range strings.Split(value, sectionsSeparator) {
+		section := Section(strings.TrimSpace(name))
+		if slices.Contains(allSections, section) && !slices.Contains(sections, section) {
+			sections = append(sections, section)
+		}
+	}
+
+	return sections
+}
+
+// formatSections собирает список разделов для сохранения
+func formatSections(sections []Section) string {
+	names := make([]string, 0, len(sections))
+	for _, section := range sections {
+		names = append(names, string(section))
+	}
+
+	return strings.Join(names, sectionsSeparator)
+}
@@ -0,0 +1,1085 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Экраны меню настроек
+const (
+	screenMain     = "main"
+	screenLocation = "location"
+	screenTime     = "time"
+	screenDays     = "days"
+	screenSections = "sections"
+	screenAlerts   = "alerts"
+	screenLanguage = "language"
+	screenUnits    = "units"
+	screenPlaces   = "places"
+	screenCommute  = "commute"
+)
+
+// settingsDataSeparator разделяет экран, действие и аргумент в данных кнопки
+const settingsDataSeparator = "|"
+
+// btnSettings - общая кнопка меню настроек; все кнопки меню отличаются только данными
+var btnSettings = tele.InlineButton{Unique: "settings"}
+
+// deliveryTimeOptions - варианты времени рассылки на экране выбора (минуты от начала суток)
+var deliveryTimeOptions = []int{
+	5 * 60, 5*60 + 30, 6 * 60, 6*60 + 30,
+	7 * 60, 7*60 + 30, 8 * 60, 8*60 + 30,
+	9 * 60, 9*60 + 30, 10 * 60, 10*60 + 30,
+}
+
+// weekdaysOrder - дни недели в порядке отображения, начиная с понедельника
+var weekdaysOrder = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+const (
+	workdaysMask = 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday
+	weekendsMask = 1<<time.Saturday | 1<<time.Sunday
+)
+
+// settingsAction - действие на экране настроек. Возвращает текст уведомления для пользователя.
+type settingsAction func(ctx context.Context, user *storage.User, arg string) (string, error)
+
+// settingsScreen описывает экран меню настроек
+type settingsScreen struct {
+	// render формирует текст и клавиатуру экрана
+	render func(ctx context.Context, user *storage.User) (string, [][]tele.InlineButton)
+	// actions - действия экрана по имени
+	actions map[string]settingsAction
+}
+
+// settingsButton создает кнопку меню, открывающую экран screen и выполняющую на нем действие action
+func settingsButton(text, screen, action, arg string) tele.InlineButton {
+	button := btnSettings
+	button.Text = text
+	button.Data = strings.Join([]string{screen, action, arg}, settingsDataSeparator)
+
+	return button
+}
+
+// backButton создает кнопку возврата на главный экран настроек
+func backButton(lang i18n.Lang) tele.InlineButton {
+	return settingsButton(lang.T("settings.back"), screenMain, "", "")
+}
+
+// registerSettingsScreens регистрирует экраны меню настроек
+func (s *ApplicationBot) registerSettingsScreens() {
+	s.settingsScreens = map[string]*settingsScreen{
+		screenMain: {
+			render: s.renderMainSettings,
+			actions: map[string]settingsAction{
+				"weather": s.toggleWeather,
+				"resume":  s.resumeWeather,
+				"digest":  s.toggleDigest,
+			},
+		},
+		screenLocation: {
+			render: s.renderLocationSettings,
+			actions: map[string]settingsAction{
+				"enter": s.enterLocation,
+				"reset": s.resetLocation,
+			},
+		},
+		screenTime: {
+			render: s.renderTimeSettings,
+			actions: map[string]settingsAction{
+				"set":   s.setDeliveryTime,
+				"enter": s.enterDeliveryTime,
+				"reset": s.resetDeliveryTime,
+			},
+		},
+		screenDays: {
+			render: s.renderDaysSettings,
+			actions: map[string]settingsAction{
+				"toggle": s.toggleDeliveryDay,
+				"preset": s.setDeliveryDaysPreset,
+			},
+		},
+		screenSections: {
+			render: s.renderSectionsSettings,
+			actions: map[string]settingsAction{
+				"toggle": s.toggleSection,
+				"up":     s.moveSectionUp,
+				"chart":  s.toggleChart,
+			},
+		},
+		screenAlerts: {
+			render: s.renderAlertsSettings,
+			actions: map[string]settingsAction{
+				"toggle": s.toggleAlertRule,
+			},
+		},
+		screenLanguage: {
+			render: s.renderLanguageSettings,
+			actions: map[string]settingsAction{
+				"set": s.setLanguage,
+			},
+		},
+		screenUnits: {
+			render: s.renderUnitsSettings,
+			actions: map[string]settingsAction{
+				"temperature": s.setTemperatureUnit,
+				"speed":       s.setSpeedUnit,
+				"pressure":    s.setPressureUnit,
+			},
+		},
+		screenPlaces: {
+			render: s.renderPlacesSettings,
+			actions: map[string]settingsAction{
+				"add":    s.enterPlace,
+				"toggle": s.togglePlaceInMorning,
+				"delete": s.deletePlace,
+			},
+		},
+		screenCommute: {
+			render: s.renderCommuteSettings,
+			actions: map[string]settingsAction{
+				"toggle":    s.toggleCommute,
+				"home":      s.nextCommuteHome,
+				"work":      s.nextCommuteWork,
+				"departure": s.enterCommuteDeparture,
+				"return":    s.enterCommuteReturn,
+			},
+		},
+	}
+}
+
+// handleSettings обрабатывает команду /settings
+func (s *ApplicationBot) handleSettings(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	// Получаем текущие настройки пользователя
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", chatID, err)
+		return c.Send(senderLang(c).T("error.not_registered"))
+	}
+
+	text, keyboard := s.settingsScreens[screenMain].render(ctx, user)
+
+	return c.Send(text, &tele.ReplyMarkup{InlineKeyboard: keyboard}, tele.ModeHTML)
+}
+
+// handleSettingsCallback обрабатывает нажатия кнопок меню настроек: выполняет действие
+// и перерисовывает экран в том же сообщении
+func (s *ApplicationBot) handleSettingsCallback(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	parts := strings.SplitN(c.Data(), settingsDataSeparator, 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	screenName, actionName, arg := parts[0], parts[1], parts[2]
+
+	screen, ok := s.settingsScreens[screenName]
+	if !ok {
+		screen = s.settingsScreens[screenMain]
+	}
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", chatID, err)
+		return c.Respond(
+			&tele.CallbackResponse{
+				Text: senderLang(c).T("error.not_registered"),
+			},
+		)
+	}
+
+	var notice string
+
+	if actionName != "" {
+		action, ok := screen.actions[actionName]
+		if !ok {
+			log.Printf("Unknown settings action %q on screen %q", actionName, screenName)
+			return c.Respond()
+		}
+
+		notice, err = action(ctx, user, arg)
+		if err != nil {
+			log.Printf("Failed to apply settings action %q for user %d: %v", actionName, chatID, err)
+			return c.Respond(
+				&tele.CallbackResponse{
+					Text: userLang(user).T("error.generic"),
+				},
+			)
+		}
+
+		user, err = s.storage.GetUser(ctx, chatID)
+		if err != nil {
+			log.Printf("Failed to get user %d: %v", chatID, err)
+			return c.Respond()
+		}
+	}
+
+	text, keyboard := screen.render(ctx, user)
+
+	if err := c.Edit(text, &tele.ReplyMarkup{InlineKeyboard: keyboard}, tele.ModeHTML); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: notice})
+}
+
+// renderMainSettings формирует главный экран настроек
+func (s *ApplicationBot) renderMainSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+	now := s.now()
+
+	weatherStatus := onOff(lang, user.WeatherEnabled)
+	if user.WeatherEnabled && isPaused(user, now) {
+		weatherStatus = lang.T("settings.paused", formatDay(lang, user.PausedUntil.In(now.Location())))
+	}
+
+	text := settingsText(
+		lang,
+		"settings.main",
+		weatherStatus,
+		s.weatherService.LocationForUser(user).Name,
+		formatMinuteOfDay(s.deliveryTime(user)),
+		formatDeliveryDays(lang, user.DeliveryDays),
+		onOff(lang, user.DigestEnabled),
+		lang.Name(),
+		formatUnits(lang, unitsForUser(user)),
+	)
+
+	weatherText := lang.T("settings.weather_on")
+	if user.WeatherEnabled {
+		weatherText = lang.T("settings.weather_off")
+	}
+
+	digestText := lang.T("settings.digest_on")
+	if user.DigestEnabled {
+		digestText = lang.T("settings.digest_off")
+	}
+
+	keyboard := [][]tele.InlineButton{
+		{settingsButton(weatherText, screenMain, "weather", "")},
+	}
+
+	if user.WeatherEnabled && isPaused(user, now) {
+		keyboard = append(
+			keyboard,
+			[]tele.InlineButton{settingsButton(lang.T("button.resume"), screenMain, "resume", "")},
+		)
+	}
+
+	keyboard = append(
+		keyboard,
+		[]tele.InlineButton{
+			settingsButton(lang.T("settings.location"), screenLocation, "", ""),
+			settingsButton(lang.T("settings.time"), screenTime, "", ""),
+		},
+		[]tele.InlineButton{
+			settingsButton(lang.T("settings.days"), screenDays, "", ""),
+			settingsButton(lang.T("settings.sections"), screenSections, "", ""),
+		},
+		[]tele.InlineButton{
+			settingsButton(lang.T("settings.alerts"), screenAlerts, "", ""),
+			settingsButton(lang.T("settings.language"), screenLanguage, "", ""),
+		},
+		[]tele.InlineButton{
+			settingsButton(lang.T("settings.places"), screenPlaces, "", ""),
+			settingsButton(lang.T("settings.units"), screenUnits, "", ""),
+		},
+		[]tele.InlineButton{settingsButton(lang.T("settings.commute"), screenCommute, "", "")},
+		[]tele.InlineButton{settingsButton(digestText, screenMain, "digest", "")},
+	)
+
+	return text, keyboard
+}
+
+// renderLocationSettings формирует экран выбора города
+func (s *ApplicationBot) renderLocationSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+
+	text := settingsText(lang, "settings.location.text", s.weatherService.LocationForUser(user).Name)
+
+	keyboard := [][]tele.InlineButton{
+		{settingsButton(lang.T("settings.location.enter"), screenLocation, "enter", "")},
+	}
+
+	if user.LocationName != "" {
+		keyboard = append(
+			keyboard, []tele.InlineButton{
+				settingsButton(
+					lang.T("settings.location.reset", s.weatherService.DefaultLocation().Name),
+					screenLocation, "reset", "",
+				),
+			},
+		)
+	}
+
+	keyboard = append(keyboard, []tele.InlineButton{backButton(lang)})
+
+	return text, keyboard
+}
+
+// renderTimeSettings формирует экран выбора времени рассылки
+func (s *ApplicationBot) renderTimeSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+	current := s.deliveryTime(user)
+
+	text := settingsText(lang, "settings.time.text", formatMinuteOfDay(current), s.timezone)
+
+	var keyboard [][]tele.InlineButton
+	var row []tele.InlineButton
+
+	for _, minute := range deliveryTimeOptions {
+		label := formatMinuteOfDay(minute)
+		if minute == current {
+			label = "• " + label
+		}
+
+		row = append(row, settingsButton(label, screenTime, "set", strconv.Itoa(minute)))
+		if len(row) == 4 {
+			keyboard = append(keyboard, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+
+	keyboard = append(
+		keyboard,
+		[]tele.InlineButton{settingsButton(lang.T("settings.time.enter"), screenTime, "enter", "")},
+		[]tele.InlineButton{
+			settingsButton(
+				lang.T("settings.time.reset", formatMinuteOfDay(s.defaultDeliveryTime)),
+				screenTime, "reset", "",
+			),
+		},
+		[]tele.InlineButton{backButton(lang)},
+	)
+
+	return text, keyboard
+}
+
+// renderDaysSettings формирует экран выбора дней рассылки
+func (s *ApplicationBot) renderDaysSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+
+	text := settingsText(lang, "settings.days.text", formatDeliveryDays(lang, user.DeliveryDays))
+
+	var days []tele.InlineButton
+	for _, weekday := range weekdaysOrder {
+		mark := "▫️"
+		if user.DeliveryDays&(1<<weekday) != 0 {
+			mark = "✅"
+		}
+
+		days = append(
+			days,
+			settingsButton(mark+" "+weekdayName(lang, weekday), screenDays, "toggle", strconv.Itoa(int(weekday))),
+		)
+	}
+
+	keyboard := [][]tele.InlineButton{
+		days[:4],
+		days[4:],
+		{
+			settingsButton(lang.T("settings.days.every_day"), screenDays, "preset", strconv.Itoa(storage.AllDeliveryDays)),
+			settingsButton(lang.T("settings.days.workdays"), screenDays, "preset", strconv.Itoa(workdaysMask)),
+			settingsButton(lang.T("settings.days.weekends"), screenDays, "preset", strconv.Itoa(weekendsMask)),
+		},
+		{backButton(lang)},
+	}
+
+	return text, keyboard
+}
+
+// renderSectionsSettings формирует экран разделов утреннего сообщения
+func (s *ApplicationBot) renderSectionsSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+
+	enabled := sectionsForUser(user)
+
+	names := make([]string, 0, len(enabled))
+	for _, section := range enabled {
+		names = append(names, lang.T("section."+string(section)))
+	}
+
+	text := settingsText(lang, "settings.sections.text", strings.Join(names, " → "), onOff(lang, user.ChartEnabled))
+
+	// Сначала включенные разделы в порядке вывода, затем выключенные
+	sections := slices.Clone(enabled)
+	for _, section := range allSections {
+		if !slices.Contains(sections, section) {
+			sections = append(sections, section)
+		}
+	}
+
+	keyboard := make([][]tele.InlineButton, 0, len(sections)+2)
+	for i, section := range sections {
+		isEnabled := i < len(enabled)
+
+		row := []tele.InlineButton{
+			settingsButton(
+				toggleLabel(isEnabled, lang.T("section."+string(section))),
+				screenSections, "toggle", string(section),
+			),
+		}
+		if isEnabled && i > 0 {
+			row = append(row, settingsButton("⬆️", screenSections, "up", string(section)))
+		}
+
+		keyboard = append(keyboard, row)
+	}
+
+	keyboard = append(
+		keyboard,
+		[]tele.InlineButton{
+			settingsButton(
+				toggleLabel(user.ChartEnabled, lang.T("settings.sections.chart")),
+				screenSections, "chart", "",
+			),
+		},
+		[]tele.InlineButton{backButton(lang)},
+	)
+
+	return text, keyboard
+}
+
+// renderAlertsSettings формирует экран правил предупреждений
+func (s *ApplicationBot) renderAlertsSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+
+	text := settingsText(lang, "settings.alerts.text")
+
+	var keyboard [][]tele.InlineButton
+	for _, info := range alertRules {
+		keyboard = append(
+			keyboard, []tele.InlineButton{
+				settingsButton(
+					toggleLabel(user.AlertRules&int(info.Rule) != 0, lang.T("alert_rule."+info.Key)),
+					screenAlerts, "toggle", info.Key,
+				),
+			},
+		)
+	}
+
+	keyboard = append(keyboard, []tele.InlineButton{backButton(lang)})
+
+	return text, keyboard
+}
+
+// renderLanguageSettings формирует экран выбора языка
+func (s *ApplicationBot) renderLanguageSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+
+	text := settingsText(lang, "settings.language.text", lang.Name())
+
+	auto := lang.T("settings.language.auto", i18n.FromTelegram(user.LanguageCode).Name())
+	if user.Language == "" {
+		auto = "• " + auto
+	}
+
+	keyboard := [][]tele.InlineButton{
+		{settingsButton(auto, screenLanguage, "set", "")},
+	}
+
+	var row []tele.InlineButton
+	for _, language := range i18n.Languages {
+		label := language.Name()
+		if user.Language == string(language) {
+			label = "• " + label
+		}
+
+		row = append(row, settingsButton(label, screenLanguage, "set", string(language)))
+	}
+
+	keyboard = append(keyboard, row, []tele.InlineButton{backButton(lang)})
+
+	return text, keyboard
+}
+
+// renderUnitsSettings формирует экран выбора единиц измерения
+func (s *ApplicationBot) renderUnitsSettings(_ context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+	preferences := unitsForUser(user)
+
+	text := settingsText(
+		lang,
+		"settings.units.text",
+		unitSymbol(lang, preferences.Temperature),
+		unitSymbol(lang, preferences.Speed),
+		unitSymbol(lang, preferences.Pressure),
+	)
+
+	keyboard := [][]tele.InlineButton{
+		unitButtons(lang, "temperature", units.Temperatures, preferences.Temperature),
+		unitButtons(lang, "speed", units.Speeds, preferences.Speed),
+		unitButtons(lang, "pressure", units.Pressures, preferences.Pressure),
+		{backButton(lang)},
+	}
+
+	return text, keyboard
+}
+
+// renderPlacesSettings формирует экран сохраненных мест
+func (s *ApplicationBot) renderPlacesSettings(ctx context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+
+	locations, err := s.locations.GetUserLocations(ctx, user.ChatID)
+	if err != nil {
+		log.Printf("Failed to get locations of user %d: %v", user.ChatID, err)
+	}
+
+	text := settingsText(lang, "settings.places.text", s.weatherService.LocationForUser(user).Name)
+	if len(locations) == 0 {
+		text += "\n\n" + lang.T("settings.places.empty")
+	}
+
+	keyboard := make([][]tele.InlineButton, 0, len(locations)+2)
+	for _, location := range locations {
+		id := strconv.FormatUint(uint64(location.ID), 10)
+
+		keyboard = append(
+			keyboard, []tele.InlineButton{
+				settingsButton(toggleLabel(location.InMorning, location.Name), screenPlaces, "toggle", id),
+				settingsButton("🗑", screenPlaces, "delete", id),
+			},
+		)
+	}
+
+	if len(locations) < maxUserLocations {
+		keyboard = append(
+			keyboard,
+			[]tele.InlineButton{settingsButton(lang.T("settings.places.add"), screenPlaces, "add", "")},
+		)
+	}
+
+	keyboard = append(keyboard, []tele.InlineButton{backButton(lang)})
+
+	return text, keyboard
+}
+
+// renderCommuteSettings формирует экран настройки дороги на работу
+func (s *ApplicationBot) renderCommuteSettings(ctx context.Context, user *storage.User) (
+	string,
+	[][]tele.InlineButton,
+) {
+	lang := userLang(user)
+	home := s.commuteLocationName(ctx, user, user.CommuteHome)
+	work := s.commuteLocationName(ctx, user, user.CommuteWork)
+	departure := formatMinuteOfDay(user.CommuteDeparture)
+	ret := formatMinuteOfDay(user.CommuteReturn)
+
+	text := settingsText(
+		lang,
+		"settings.commute.text",
+		onOff(lang, user.CommuteEnabled),
+		home,
+		departure,
+		work,
+		ret,
+		s.timezone,
+	)
+
+	keyboard := [][]tele.InlineButton{
+		{
+			settingsButton(
+				toggleLabel(user.CommuteEnabled, lang.T("settings.commute.toggle")),
+				screenCommute, "toggle", "",
+			),
+		},
+		{
+			settingsButton(lang.T("settings.commute.home", home), screenCommute, "home", ""),
+			settingsButton(lang.T("settings.commute.work", work), screenCommute, "work", ""),
+		},
+		{
+			settingsButton(lang.T("settings.commute.departure", departure), screenCommute, "departure", ""),
+			settingsButton(lang.T("settings.commute.return", ret), screenCommute, "return", ""),
+		},
+		{backButton(lang)},
+	}
+
+	return text, keyboard
+}
+
+// unitButtons создает ряд кнопок выбора единицы измерения, отмечая текущую
+func unitButtons[U ~string](lang i18n.Lang, action string, options []U, current U) []tele.InlineButton {
+	row := make([]tele.InlineButton, 0, len(options))
+
+	for _, unit := range options {
+		label := unitSymbol(lang, unit)
+		if unit == current {
+			label = "• " + label
+		}
+
+		row = append(row, settingsButton(label, screenUnits, action, string(unit)))
+	}
+
+	return row
+}
+
+// toggleWeather включает или выключает утреннюю рассылку
+func (s *ApplicationBot) toggleWeather(ctx context.Context, user *storage.User, _ string) (string, error) {
+	if err := s.storage.UpdateWeatherEnabled(ctx, user.ChatID, !user.WeatherEnabled); err != nil {
+		return "", err
+	}
+
+	if user.WeatherEnabled {
+		return userLang(user).T("settings.weather_disabled"), nil
+	}
+
+	return userLang(user).T("settings.weather_enabled"), nil
+}
+
+// resumeWeather снимает паузу утренней рассылки
+func (s *ApplicationBot) resumeWeather(ctx context.Context, user *storage.User, _ string) (string, error) {
+	if err := s.storage.UpdatePausedUntil(ctx, user.ChatID, nil); err != nil {
+		return "", err
+	}
+
+	return userLang(user).T("pause.resumed"), nil
+}
+
+// toggleDigest включает или выключает сводку погоды
+func (s *ApplicationBot) toggleDigest(ctx context.Context, user *storage.User, _ string) (string, error) {
+	if err := s.storage.UpdateDigestEnabled(ctx, user.ChatID, !user.DigestEnabled); err != nil {
+		return "", err
+	}
+
+	if user.DigestEnabled {
+		return userLang(user).T("settings.digest_disabled"), nil
+	}
+
+	return userLang(user).T("settings.digest_enabled"), nil
+}
+
+// enterLocation начинает диалог ввода города
+func (s *ApplicationBot) enterLocation(ctx context.Context, user *storage.User, _ string) (string, error) {
+	return "", s.startDialog(ctx, user, dialogFlowLocation)
+}
+
+// resetLocation возвращает город по умолчанию
+func (s *ApplicationBot) resetLocation(ctx context.Context, user *storage.User, _ string) (string, error) {
+	if err := s.storage.UpdateLocation(ctx, user.ChatID, "", 0, 0); err != nil {
+		return "", err
+	}
+
+	return userLang(user).T("settings.location.reset_done"), nil
+}
+
+// setDeliveryTime устанавливает время рассылки
+func (s *ApplicationBot) setDeliveryTime(ctx context.Context, user *storage.User, arg string) (string, error) {
+	minute, err := strconv.Atoi(arg)
+	if err != nil || minute < 0 || minute >= 24*60 {
+		return "", fmt.Errorf("invalid delivery time %q", arg)
+	}
+
+	if err := s.storage.UpdateDeliveryTime(ctx, user.ChatID, &minute); err != nil {
+		return "", err
+	}
+
+	return userLang(user).T("settings.time.set", formatMinuteOfDay(minute)), nil
+}
+
+// enterDeliveryTime начинает диалог ввода произвольного времени рассылки
+func (s *ApplicationBot) enterDeliveryTime(ctx context.Context, user *storage.User, _ string) (string, error) {
+	return "", s.startDialog(ctx, user, dialogFlowTime)
+}
+
+// resetDeliveryTime возвращает время рассылки по умолчанию
+func (s *ApplicationBot) resetDeliveryTime(ctx context.Context, user *storage.User, _ string) (string, error) {
+	if err := s.storage.UpdateDeliveryTime(ctx, user.ChatID, nil); err != nil {
+		return "", err
+	}
+
+	return userLang(user).T("settings.time.set", formatMinuteOfDay(s.defaultDeliveryTime)), nil
+}
+
+// toggleDeliveryDay включает или выключает день недели рассылки
+func (s *ApplicationBot) toggleDeliveryDay(ctx context.Context, user *storage.User, arg string) (string, error) {
+	weekday, err := strconv.Atoi(arg)
+	if err != nil || weekday < 0 || weekday > 6 {
+		return "", fmt.Errorf("invalid weekday %q", arg)
+	}
+
+	days := user.DeliveryDays ^ 1<<weekday
+	if days == 0 {
+		return userLang(user).T("settings.days.at_least_one"), nil
+	}
+
+	if err := s.storage.UpdateDeliveryDays(ctx, user.ChatID, days); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+// setDeliveryDaysPreset устанавливает дни рассылки из готового набора
+func (s *ApplicationBot) setDeliveryDaysPreset(ctx context.Context, user *storage.User, arg string) (string, error) {
+	days, err := strconv.Atoi(arg)
+	if err != nil || days <= 0 || days > storage.AllDeliveryDays {
+		return "", fmt.Errorf("invalid delivery days %q", arg)
+	}
+
+	if err := s.storage.UpdateDeliveryDays(ctx, user.ChatID, days); err != nil {
+		return "", err
+	}
+
+	return userLang(user).T("settings.days.set", formatDeliveryDays(userLang(user), days)), nil
+}
+
+// toggleSection включает раздел утреннего сообщения (в конец списка) или выключает его
+func (s *ApplicationBot) toggleSection(ctx context.Context, user *storage.User, arg string) (string, error) {
+	section := Section(arg)
+	if !slices.Contains(allSections, section) {
+		return "", fmt.Errorf("unknown section %q", arg)
+	}
+
+	sections := sectionsForUser(user)
+
+	if i := slices.Index(sections, section); i >= 0 {
+		if len(sections) == 1 {
+			return userLang(user).T("settings.sections.at_least_one"), nil
+		}
+
+		sections = slices.Delete(sections, i, i+1)
+	} else {
+		sections = append(sections, section)
+	}
+
+	return "", s.storage.UpdateSections(ctx, user.ChatID, formatSections(sections))
+}
+
+// moveSectionUp поднимает включенный раздел на одну позицию выше
+func (s *ApplicationBot) moveSectionUp(ctx context.Context, user *storage.User, arg string) (string, error) {
+	sections := sectionsForUser(user)
+
+	i := slices.Index(sections, Section(arg))
+	if i < 0 {
+		return "", fmt.Errorf("section %q is not enabled", arg)
+	}
+	if i == 0 {
+		return "", nil
+	}
+
+	sections[i-1], sections[i] = sections[i], sections[i-1]
+
+	return "", s.storage.UpdateSections(ctx, user.ChatID, formatSections(sections))
+}
+
+// toggleChart включает или выключает прикрепление графика к утренней рассылке
+func (s *ApplicationBot) toggleChart(ctx context.Context, user *storage.User, _ string) (string, error) {
+	return "", s.storage.UpdateChartEnabled(ctx, user.ChatID, !user.ChartEnabled)
+}
+
+// toggleAlertRule включает или выключает правило предупреждений
+func (s *ApplicationBot) toggleAlertRule(ctx context.Context, user *storage.User, arg string) (string, error) {
+	for _, info := range alertRules {
+		if info.Key == arg {
+			return "", s.storage.UpdateAlertRules(ctx, user.ChatID, user.AlertRules^int(info.Rule))
+		}
+	}
+
+	return "", fmt.Errorf("unknown alert rule %q", arg)
+}
+
+// setLanguage устанавливает язык сообщений; пустой аргумент возвращает язык Telegram
+func (s *ApplicationBot) setLanguage(ctx context.Context, user *storage.User, arg string) (string, error) {
+	lang := i18n.FromTelegram(user.LanguageCode)
+
+	if arg != "" {
+		var ok bool
+		if lang, ok = i18n.Parse(arg); !ok {
+			return "", fmt.Errorf("unsupported language %q", arg)
+		}
+	}
+
+	if err := s.storage.UpdateLanguage(ctx, user.ChatID, arg); err != nil {
+		return "", err
+	}
+
+	return lang.T("settings.language.set", lang.Name()), nil
+}
+
+// settingsText форматирует текст экрана настроек. Экраны отправляются с разметкой HTML,
+// поэтому подставляемые значения (названия мест, часовой пояс) экранируются.
+func settingsText(lang i18n.Lang, key string, args ...string) string {
+	escaped := make([]any, len(args))
+	for i, arg := range args {
+		escaped[i] = html.EscapeString(arg)
+	}
+
+	return lang.T(key, escaped...)
+}
+
+// deliveryTime возвращает время рассылки пользователя в минутах от начала суток
+func (s *ApplicationBot) deliveryTime(user *storage.User) int {
+	if user.DeliveryTime == nil {
+		return s.defaultDeliveryTime
+	}
+
+	return *user.DeliveryTime
+}
+
+// formatMinuteOfDay форматирует минуту от начала суток в виде "07:30"
+func formatMinuteOfDay(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// formatDeliveryDays форматирует маску дней рассылки
+func formatDeliveryDays(lang i18n.Lang, days int) string {
+	switch days {
+	case storage.AllDeliveryDays:
+		return lang.T("days.every_day")
+	case workdaysMask:
+		return lang.T("days.workdays")
+	case weekendsMask:
+		return lang.T("days.weekends")
+	}
+
+	var names []string
+	for _, weekday := range weekdaysOrder {
+		if days&(1<<weekday) != 0 {
+			names = append(names, weekdayName(lang, weekday))
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// onOff возвращает "вкл" или "выкл"
+func onOff(lang i18n.Lang, enabled bool) string {
+	if enabled {
+		return lang.T("settings.on")
+	}
+
+	return lang.T("settings.off")
+}
+
+// toggleLabel добавляет к названию настройки отметку ее состояния
+func toggleLabel(enabled bool, name string) string {
+	if enabled {
+		return "✅ " + name
+	}
+
+	return "▫️ " + name
+}
+
+// setTemperatureUnit устанавливает единицу измерения температуры
+func (s *ApplicationBot) setTemperatureUnit(ctx context.Context, user *storage.User, arg string) (string, error) {
+	preferences := unitsForUser(user)
+
+	preferences.Temperature = units.Temperature(arg)
+	if !slices.Contains(units.Temperatures, preferences.Temperature) {
+		return "", fmt.Errorf("unknown temperature unit %q", arg)
+	}
+
+	return s.updateUnits(ctx, user, preferences)
+}
+
+// setSpeedUnit устанавливает единицу измерения скорости ветра
+func (s *ApplicationBot) setSpeedUnit(ctx context.Context, user *storage.User, arg string) (string, error) {
+	preferences := unitsForUser(user)
+
+	preferences.Speed = units.Speed(arg)
+	if !slices.Contains(units.Speeds, preferences.Speed) {
+		return "", fmt.Errorf("unknown speed unit %q", arg)
+	}
+
+	return s.updateUnits(ctx, user, preferences)
+}
+
+// setPressureUnit устанавливает единицу измерения давления
+func (s *ApplicationBot) setPressureUnit(ctx context.Context, user *storage.User, arg string) (string, error) {
+	preferences := unitsForUser(user)
+
+	preferences.Pressure = units.Pressure(arg)
+	if !slices.Contains(units.Pressures, preferences.Pressure) {
+		return "", fmt.Errorf("unknown pressure unit %q", arg)
+	}
+
+	return s.updateUnits(ctx, user, preferences)
+}
+
+// updateUnits сохраняет единицы измерения пользователя
+func (s *ApplicationBot) updateUnits(
+	ctx context.Context,
+	user *storage.User,
+	preferences units.Preferences,
+) (string, error) {
+	err := s.storage.UpdateUnits(
+		ctx,
+		user.ChatID,
+		string(preferences.Temperature),
+		string(preferences.Speed),
+		string(preferences.Pressure),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	lang := userLang(user)
+
+	return lang.T("settings.units.set", formatUnits(lang, preferences)), nil
+}
+
+// enterPlace начинает диалог добавления сохраненного места
+func (s *ApplicationBot) enterPlace(ctx context.Context, user *storage.User, _ string) (string, error) {
+	locations, err := s.locations.GetUserLocations(ctx, user.ChatID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(locations) >= maxUserLocations {
+		return userLang(user).T("location.limit", maxUserLocations), nil
+	}
+
+	return "", s.startDialog(ctx, user, dialogFlowPlace)
+}
+
+// togglePlaceInMorning включает или выключает сохраненное место в утреннем сообщении
+func (s *ApplicationBot) togglePlaceInMorning(ctx context.Context, user *storage.User, arg string) (string, error) {
+	id, err := parseLocationID(arg)
+	if err != nil {
+		return "", err
+	}
+
+	location, err := s.locations.GetUserLocation(ctx, user.ChatID, id)
+	if err != nil {
+		return "", err
+	}
+
+	return "", s.locations.UpdateUserLocationInMorning(ctx, user.ChatID, id, !location.InMorning)
+}
+
+// deletePlace удаляет сохраненное место
+func (s *ApplicationBot) deletePlace(ctx context.Context, user *storage.User, arg string) (string, error) {
+	id, err := parseLocationID(arg)
+	if err != nil {
+		return "", err
+	}
+
+	location, err := s.locations.GetUserLocation(ctx, user.ChatID, id)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.locations.DeleteUserLocation(ctx, user.ChatID, id); err != nil {
+		return "", err
+	}
+
+	// Дорога с удаленным местом переключается на основной город
+	if user.CommuteHome == id || user.CommuteWork == id {
+		home, work := user.CommuteHome, user.CommuteWork
+		if home == id {
+			home = mainLocationID
+		}
+		if work == id {
+			work = mainLocationID
+		}
+
+		if err := s.storage.UpdateCommuteLocations(ctx, user.ChatID, home, work); err != nil {
+			return "", err
+		}
+	}
+
+	return userLang(user).T("settings.places.deleted", location.Name), nil
+}
+
+// toggleCommute включает или выключает прогноз на дорогу. При включении раздел дороги
+// добавляется в утреннее сообщение, если пользователь убрал его раньше.
+func (s *ApplicationBot) toggleCommute(ctx context.Context, user *storage.User, _ string) (string, error) {
+	if err := s.storage.UpdateCommuteEnabled(ctx, user.ChatID, !user.CommuteEnabled); err != nil {
+		return "", err
+	}
+
+	lang := userLang(user)
+
+	if user.CommuteEnabled {
+		return lang.T("settings.commute.disabled"), nil
+	}
+
+	sections := sectionsForUser(user)
+	if !slices.Contains(sections, SectionCommute) {
+		sections = append(sections, SectionCommute)
+		if err := s.storage.UpdateSections(ctx, user.ChatID, formatSections(sections)); err != nil {
+			return "", err
+		}
+	}
+
+	return lang.T("settings.commute.enabled"), nil
+}
+
+// nextCommuteHome переключает место дома на следующее из основного города и сохраненных мест
+func (s *ApplicationBot) nextCommuteHome(ctx context.Context, user *storage.User, _ string) (string, error) {
+	home := nextCommuteLocation(s.commuteLocationIDs(ctx, user), user.CommuteHome)
+
+	return "", s.storage.UpdateCommuteLocations(ctx, user.ChatID, home, user.CommuteWork)
+}
+
+// nextCommuteWork переключает место работы на следующее из основного города и сохраненных мест
+func (s *ApplicationBot) nextCommuteWork(ctx context.Context, user *storage.User, _ string) (string, error) {
+	work := nextCommuteLocation(s.commuteLocationIDs(ctx, user), user.CommuteWork)
+
+	return "", s.storage.UpdateCommuteLocations(ctx, user.ChatID, user.CommuteHome, work)
+}
+
+// nextCommuteLocation возвращает ID, следующий за current по кругу.
+// Если current среди ids нет, возвращается первый ID.
+func nextCommuteLocation(ids []uint, current uint) uint {
+	i := slices.Index(ids, current)
+
+	return ids[(i+1)%len(ids)]
+}
+
+// enterCommuteDeparture начинает диалог ввода времени выезда
+func (s *ApplicationBot) enterCommuteDeparture(ctx context.Context, user *storage.User, _ string) (string, error) {
+	return "", s.startDialog(ctx, user, dialogFlowCommuteDeparture)
+}
+
+// enterCommuteReturn начинает диалог ввода времени возвращения
+func (s *ApplicationBot) enterCommuteReturn(ctx context.Context, user *storage.User, _ string) (string, error) {
+	return "", s.startDialog(ctx, user, dialogFlowCommuteReturn)
+}
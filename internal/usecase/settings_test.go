@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+)
+
+func TestSettingsTextEscapesValues(t *testing.T) {
+	for _, lang := range i18n.Languages {
+		got := settingsText(lang, "settings.location.text", "Tom & <Jerry>_*`")
+
+		if !strings.Contains(got, "<b>Tom &amp; &lt;Jerry&gt;_*`</b>") {
+			t.Errorf("%s: settingsText() = %q, want escaped location name", lang, got)
+		}
+	}
+}
+
+func TestSettingsTextEscapesCommutePlaces(t *testing.T) {
+	for _, lang := range i18n.Languages {
+		got := settingsText(
+			lang,
+			"settings.commute.text",
+			lang.T("settings.on"),
+			"my_home",
+			"08:30",
+			"<b>*Office*</b>",
+			"19:00",
+			"America/New_York",
+		)
+
+		for _, want := range []string{"<b>my_home</b>", "<b>&lt;b&gt;*Office*&lt;/b&gt;</b>", "America/New_York"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: settingsText() = %q, want it to contain %q", lang, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"text/template"
+	"time"
+
+	"github.com/qrave1/DeepCakeBot/internal/astro"
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/condition"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/templates"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+)
+
+// messageEmoji - значки строк сообщений, доступные в шаблонах через emoji
+var messageEmoji = map[string]string{
+	"temperature": "🌡",
+	"humidity":    "💧",
+	"wind":        "💨",
+	"pressure":    "🔽",
+	"air":         "🍃",
+}
+
+// weatherMessage - данные шаблона сообщения с прогнозом погоды
+type weatherMessage struct {
+	LocationName string
+	Weather      *openweather.WeatherData
+	// Air - качество воздуха, nil если раздел выключен или данных нет
+	Air *openweather.AirQuality
+	// Alerts - сработавшие предупреждения, пусто если их нет
+	Alerts []Alert
+	// Comparison - сравнение со вчерашним днем, nil если сравнивать не с чем
+	Comparison *WeatherComparison
+	// Precipitation - периоды осадков по прогнозу для советов по одежде
+	Precipitation []PrecipitationWindow
+	// Astro - Солнце и Луна, nil если раздел выключен
+	Astro *AstroData
+	// Commute - прогноз на дорогу, nil если дорога не настроена
+	Commute *CommuteForecast
+}
+
+// newWeatherMessage возвращает данные шаблона для отчета о погоде
+func newWeatherMessage(report *WeatherReport) weatherMessage {
+	return weatherMessage{
+		LocationName:  report.LocationName,
+		Weather:       report.Weather,
+		Air:           report.Air,
+		Alerts:        report.Alerts,
+		Comparison:    report.Comparison,
+		Precipitation: report.Precipitation,
+		Astro:         report.Astro,
+		Commute:       report.Commute,
+	}
+}
+
+// NewMessageTemplates загружает шаблоны сообщений (встроенные и из каталога dir)
+// и проверяет, что все разделы форматируются на всех языках
+func NewMessageTemplates(dir string) (*templates.Renderer, error) {
+	renderer, err := templates.New(dir, messageFuncs(i18n.Default, units.Metric()))
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := newWeatherMessage(weatherReportFixture())
+
+	for _, lang := range i18n.Languages {
+		for _, section := range allSections {
+			_, err := renderer.Render(
+				string(section),
+				messageLocale(lang, units.Metric()),
+				fixture,
+				messageFuncs(lang, units.Metric()),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", lang, err)
+			}
+		}
+	}
+
+	return renderer, nil
+}
+
+// messageLocale возвращает ключ, от которого зависят функции messageFuncs
+func messageLocale(lang i18n.Lang, u units.Preferences) string {
+	return fmt.Sprintf("%s:%s:%s:%s", lang, u.Temperature, u.Speed, u.Pressure)
+}
+
+// messageFuncs возвращает вспомогательные функции шаблонов для языка lang и единиц u
+func messageFuncs(lang i18n.Lang, u units.Preferences) template.FuncMap {
+	return template.FuncMap{
+		"t":      lang.T,
+		"plural": lang.Plural,
+		"temp": func(celsius float64) string {
+			return formatTemperature(lang, u, celsius)
+		},
+		"speed": func(speed float64) string {
+			return formatSpeed(lang, u, speed)
+		},
+		"pressure": func(pressure float64) string {
+			return formatPressure(lang, u, pressure)
+		},
+		"degrees": func(celsius float64) float64 {
+			return u.Temperature.FromCelsius(celsius)
+		},
+		"tempDelta": func(celsius float64) float64 {
+			return math.Round(u.Temperature.DeltaFromCelsius(celsius))
+		},
+		"emoji": func(name string) string {
+			return messageEmoji[name]
+		},
+		"clothing": func(celsius float64) string {
+			return clothingForTemperature(lang, celsius)
+		},
+		"likely": func(probability float64) bool {
+			return probability >= rainyForecastProbability
+		},
+		"percent": func(probability float64) float64 {
+			return probability * 100
+		},
+		"abs":         math.Abs,
+		"hour":        formatHour,
+		"clock":       formatClock,
+		"minuteOfDay": formatMinuteOfDay,
+		"duration": func(d time.Duration) string {
+			return formatDuration(lang, d)
+		},
+		"dayLengthChange": func(d time.Duration) string {
+			return formatDayLengthChange(lang, d)
+		},
+		"moon": func(phase int) string {
+			return moonPhaseEmoji[phase]
+		},
+	}
+}
+
+// weatherReportFixture возвращает отчет о погоде со всеми разделами, по которому при запуске
+// проверяются шаблоны сообщений
+func weatherReportFixture() *WeatherReport {
+	zone := time.FixedZone("MSK", 3*60*60)
+	day := time.Date(2026, time.January, 20, 0, 0, 0, 0, zone)
+
+	return &WeatherReport{
+		LocationName: "Moscow",
+		Weather: &openweather.WeatherData{
+			City:        "Moscow",
+			Latitude:    55.75,
+			Longitude:   37.62,
+			Temperature: -3.5,
+			FeelsLike:   -8.2,
+			Condition:   condition.Snow,
+			Description: "snow",
+			Humidity:    85,
+			WindSpeed:   4.3,
+			Pressure:    1013,
+			Location:    zone,
+		},
+		Sections: allSections,
+		Alerts:   []Alert{AlertIce, AlertSnow},
+		Comparison: &WeatherComparison{
+			TemperatureDelta: -4.4,
+			WindDelta:        3.5,
+			SnowStarted:      true,
+		},
+		Astro: &AstroData{
+			Sun: astro.SunInfo{
+				Sunrise:   day.Add(8*time.Hour + 51*time.Minute),
+				Sunset:    day.Add(16*time.Hour + 34*time.Minute),
+				DayLength: 7*time.Hour + 43*time.Minute,
+			},
+			DayLengthChange: 3 * time.Minute,
+			Moon:            astro.MoonInfo{Age: 1.5, Phase: 0.05, Illumination: 0.02},
+		},
+		Air: &openweather.AirQuality{
+			Index: 2,
+			PM25:  12.4,
+			PM10:  20.1,
+		},
+		Commute: &CommuteForecast{
+			Departure: CommutePoint{
+				Minute:       8*60 + 30,
+				LocationName: "Home",
+				Entry: openweather.ForecastEntry{
+					Time:                     day.Add(9 * time.Hour),
+					Temperature:              -4.2,
+					Condition:                condition.Snow,
+					Description:              "light snow",
+					PrecipitationProbability: 0.8,
+				},
+			},
+			Return: CommutePoint{
+				Minute:       19 * 60,
+				LocationName: "Office",
+				Entry: openweather.ForecastEntry{
+					Time:        day.Add(18 * time.Hour),
+					Temperature: -6.8,
+					Condition:   condition.Clear,
+					Night:       true,
+					Description: "clear sky",
+				},
+			},
+		},
+		Precipitation: []PrecipitationWindow{
+			{
+				Snow:        true,
+				Start:       day.Add(12 * time.Hour),
+				End:         day.Add(18 * time.Hour),
+				Probability: 0.8,
+				Amount:      2.5,
+			},
+		},
+	}
+}
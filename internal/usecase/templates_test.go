@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+)
+
+// update перезаписывает эталонные сообщения: go test ./internal/usecase -run Golden -update
+var update = flag.Bool("update", false, "update golden message files")
+
+// goldenDir - каталог эталонных разделов сообщения
+const goldenDir = "testdata"
+
+func TestWeatherMessageGolden(t *testing.T) {
+	renderer, err := NewMessageTemplates("")
+	if err != nil {
+		t.Fatalf("NewMessageTemplates() error = %v", err)
+	}
+
+	service := NewWeatherService("", "Moscow", "RU", nil, 0, renderer)
+
+	preferences := []struct {
+		name  string
+		units units.Preferences
+	}{
+		{name: "metric", units: units.Metric()},
+		{name: "imperial", units: units.Parse("f", "mph", "mmhg")},
+	}
+
+	for _, section := range allSections {
+		for _, lang := range i18n.Languages {
+			for _, p := range preferences {
+				name := fmt.Sprintf("%s.%s.%s", section, lang, p.name)
+
+				t.Run(
+					name, func(t *testing.T) {
+						report := weatherReportFixture()
+						report.Sections = []Section{section}
+
+						got, err := service.FormatWeatherMessage(lang, p.units, report)
+						if err != nil {
+							t.Fatalf("FormatWeatherMessage() error = %v", err)
+						}
+
+						golden := filepath.Join(goldenDir, name+".golden")
+
+						if *update {
+							if err := os.WriteFile(golden, []byte(got+"\n"), 0o644); err != nil {
+								t.Fatalf("failed to update %s: %v", golden, err)
+							}
+						}
+
+						want, err := os.ReadFile(golden)
+						if err != nil {
+							t.Fatalf("failed to read %s (run with -update to create it): %v", golden, err)
+						}
+
+						if got+"\n" != string(want) {
+							t.Errorf("section differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+						}
+					},
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	// tripPackingDays - за сколько дней до начала поездки отправляется прогноз для сборов
+	tripPackingDays = 3
+	// maxTripDays - максимальная длительность поездки в днях
+	maxTripDays = 60
+)
+
+// Кнопки под сообщением /trip
+var (
+	btnChangeTrip = tele.InlineButton{Unique: "change_trip"}
+	btnCancelTrip = tele.InlineButton{Unique: "cancel_trip"}
+)
+
+// tripLocation возвращает место для запроса погоды по месту поездки
+func tripLocation(trip *storage.Trip) openweather.Location {
+	return openweather.Location{
+		Name:      trip.Name,
+		Latitude:  trip.Latitude,
+		Longitude: trip.Longitude,
+	}
+}
+
+// tripZone возвращает часовой пояс места поездки
+func tripZone(trip *storage.Trip) *time.Location {
+	return time.FixedZone("", trip.UTCOffset)
+}
+
+// tripDate возвращает день поездки, на который приходится t по местному времени места поездки
+func tripDate(trip *storage.Trip, t time.Time) time.Time {
+	return dateOf(t.In(tripZone(trip)))
+}
+
+// tripActive сообщает, идет ли поездка в момент t по местному времени места поездки
+func tripActive(trip *storage.Trip, t time.Time) bool {
+	date := tripDate(trip, t)
+
+	return !date.Before(trip.StartDate) && !date.After(trip.EndDate)
+}
+
+// dateOf возвращает день t в виде полуночи UTC - в таком виде хранятся даты поездок
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// handleTrip обрабатывает команду /trip: показывает поездку или начинает диалог ее создания
+func (s *ApplicationBot) handleTrip(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		return c.Send(senderLang(c).T("error.not_registered"))
+	}
+	lang := userLang(user)
+
+	trip, err := s.trips.GetTrip(ctx, chatID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrTripNotFound) {
+			log.Printf("Failed to get trip of user %d: %v", chatID, err)
+			return c.Send(lang.T("error.generic"))
+		}
+
+		return s.startDialog(ctx, user, dialogFlowTrip)
+	}
+
+	changeButton := btnChangeTrip
+	changeButton.Text = lang.T("button.change_trip")
+
+	cancelButton := btnCancelTrip
+	cancelButton.Text = lang.T("button.cancel_trip")
+
+	return c.Send(
+		lang.T("trip.info", trip.Name, formatTripDates(lang, trip), formatUTCOffset(trip.UTCOffset)),
+		&tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{changeButton, cancelButton}}},
+	)
+}
+
+// handleChangeTrip обрабатывает кнопку изменения поездки: начинает диалог заново
+func (s *ApplicationBot) handleChangeTrip(c tele.Context) error {
+	ctx := context.Background()
+
+	user, err := s.storage.GetUser(ctx, c.Chat().ID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: senderLang(c).T("error.not_registered")})
+	}
+
+	if err := s.startDialog(ctx, user, dialogFlowTrip); err != nil {
+		log.Printf("Failed to start trip dialog for user %d: %v", user.ChatID, err)
+		return c.Respond(&tele.CallbackResponse{Text: userLang(user).T("error.generic")})
+	}
+
+	return c.Respond()
+}
+
+// handleCancelTrip обрабатывает кнопку отмены поездки
+func (s *ApplicationBot) handleCancelTrip(c tele.Context) error {
+	ctx := context.Background()
+	chatID := c.Chat().ID
+
+	user, err := s.storage.GetUser(ctx, chatID)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: senderLang(c).T("error.not_registered")})
+	}
+	lang := userLang(user)
+
+	if err := s.trips.DeleteTrip(ctx, chatID); err != nil {
+		log.Printf("Failed to delete trip of user %d: %v", chatID, err)
+		return c.Respond(&tele.CallbackResponse{Text: lang.T("error.generic")})
+	}
+
+	if err := c.Edit(lang.T("trip.cancelled", s.weatherService.LocationForUser(user).Name)); err != nil {
+		log.Printf("Failed to edit message: %v", err)
+	}
+
+	return c.Respond()
+}
+
+// SendTripWeatherToUser отправляет утренний прогноз для места поездки.
+// Возвращает ID последнего отправленного сообщения или 0, если все сообщения уже были отправлены.
+func (s *ApplicationBot) SendTripWeatherToUser(
+	ctx context.Context,
+	user *storage.User,
+	trip *storage.Trip,
+	delivery MorningDelivery,
+) (int, error) {
+	if len(delivery.Messages) > 0 {
+		return s.sendMorningMessages(ctx, user, delivery, delivery.Messages)
+	}
+
+	lang := userLang(user)
+
+	report, err := s.BuildWeatherReport(ctx, user, tripLocation(trip))
+	if err != nil {
+		return 0, err
+	}
+	report.LocationName = trip.Name
+
+	message, err := s.weatherService.FormatWeatherMessage(lang, unitsForUser(user), report)
+	if err != nil {
+		return 0, err
+	}
+
+	day := int(tripDate(trip, delivery.Slot).Sub(trip.StartDate).Hours()/24) + 1
+	days := int(trip.EndDate.Sub(trip.StartDate).Hours()/24) + 1
+
+	header := lang.T("trip.morning", trip.Name, day, days)
+	messages := splitMessage([]string{header + "\n\n" + message}, locationsSeparator, morningMessageLength)
+
+	return s.sendMorningMessages(ctx, user, delivery, messages)
+}
+
+// SendPackingForecastToUser отправляет прогноз для сборов перед поездкой
+func (s *ApplicationBot) SendPackingForecastToUser(ctx context.Context, user *storage.User, trip *storage.Trip) error {
+	lang := userLang(user)
+
+	forecast, err := s.weatherService.GetForecast(ctx, tripLocation(trip), lang)
+	if err != nil {
+		return fmt.Errorf("failed to get forecast: %w", err)
+	}
+
+	message := s.weatherService.FormatPackingForecast(lang, unitsForUser(user), trip, forecast)
+
+	return s.SendTextToUser(ctx, user.ChatID, message)
+}
+
+// FormatPackingForecast форматирует прогноз на дни поездки с советами, что взять с собой.
+// Прогноз доступен на несколько дней вперед, поэтому может покрывать только начало поездки.
+func (s *WeatherService) FormatPackingForecast(
+	lang i18n.Lang,
+	u units.Preferences,
+	trip *storage.Trip,
+	entries []openweather.ForecastEntry,
+) string {
+	lines := []string{lang.T("trip.packing.title", trip.Name, formatTripDates(lang, trip))}
+
+	start := trip.StartDate
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, tripZone(trip))
+
+	var days []DailySummary
+	for _, day := range summarizeForecast(entries, from) {
+		if dateOf(day.Date).After(trip.EndDate) {
+			break
+		}
+		days = append(days, day)
+	}
+
+	if len(days) == 0 {
+		return strings.Join(append(lines, lang.T("trip.packing.no_forecast")), "\n")
+	}
+
+	minTemperature, maxTemperature := math.Inf(1), math.Inf(-1)
+	rain, snow := false, false
+
+	for _, day := range days {
+		lines = append(lines, formatForecastDay(lang, u, day))
+
+		minTemperature = math.Min(minTemperature, day.MinTemperature)
+		maxTemperature = math.Max(maxTemperature, day.MaxTemperature)
+		rain = rain || day.Rain || (day.PrecipitationProbability >= rainyForecastProbability && !day.Snow)
+		snow = snow || day.Snow
+	}
+
+	lines = append(lines, "", lang.T("trip.packing.tips"))
+
+	// Для холодных и теплых дней поездки могут понадобиться разные вещи
+	lines = append(lines, clothingForTemperature(lang, minTemperature))
+	if warm := clothingForTemperature(lang, maxTemperature); warm != lines[len(lines)-1] {
+		lines = append(lines, warm)
+	}
+
+	if rain {
+		lines = append(lines, lang.T("trip.packing.umbrella"))
+	}
+	if snow {
+		lines = append(lines, lang.T("trip.packing.snow"))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatTripDates форматирует даты поездки в виде "чт 12.03 – пт 20.03"
+func formatTripDates(lang i18n.Lang, trip *storage.Trip) string {
+	if trip.StartDate.Equal(trip.EndDate) {
+		return formatDay(lang, trip.StartDate)
+	}
+
+	return formatDay(lang, trip.StartDate) + " – " + formatDay(lang, trip.EndDate)
+}
+
+// formatUTCOffset форматирует смещение от UTC в виде "UTC+3" или "UTC+5:30"
+func formatUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	hours, minutes := offset/3600, offset%3600/60
+	if minutes == 0 {
+		return fmt.Sprintf("UTC%s%d", sign, hours)
+	}
+
+	return fmt.Sprintf("UTC%s%d:%02d", sign, hours, minutes)
+}
+
+// parseTripDates разбирает даты поездки "12.03-20.03", "12.03.2027 - 20.03.2027"
+// или один день "12.03". Год конца поездки без года - ближайший, в котором она не закончилась
+// к today; год начала - ближайший, не позже конца. Так можно указать уже начавшуюся поездку.
+func parseTripDates(text string, today time.Time) (time.Time, time.Time, error) {
+	parts := strings.FieldsFunc(
+		text, func(r rune) bool {
+			return r == '-' || r == '–' || r == '—' || unicode.IsSpace(r)
+		},
+	)
+	if len(parts) == 0 || len(parts) > 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid trip dates %q", text)
+	}
+
+	end, withYear, err := parseTripDate(parts[len(parts)-1], today.Year())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !withYear && end.Before(today) {
+		end = end.AddDate(1, 0, 0)
+	}
+
+	start, withYear, err := parseTripDate(parts[0], end.Year())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !withYear && start.After(end) {
+		start = start.AddDate(-1, 0, 0)
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("trip ends before it starts: %q", text)
+	}
+
+	return start, end, nil
+}
+
+// parseTripDate разбирает дату "ДД.ММ.ГГГГ" или "ДД.ММ" (в году year).
+// withYear сообщает, был ли год указан явно.
+func parseTripDate(text string, year int) (date time.Time, withYear bool, err error) {
+	if parsed, err := time.Parse("2.1.2006", text); err == nil {
+		return parsed, true, nil
+	}
+
+	date, err = time.Parse("2.1", text)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), false, nil
+}
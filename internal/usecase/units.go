@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+	"github.com/qrave1/DeepCakeBot/internal/units"
+)
+
+// unitsForUser возвращает единицы измерения пользователя
+func unitsForUser(user *storage.User) units.Preferences {
+	return units.Parse(user.TemperatureUnit, user.SpeedUnit, user.PressureUnit)
+}
+
+// unitSymbol возвращает обозначение единицы измерения на языке lang
+func unitSymbol[U ~string](lang i18n.Lang, unit U) string {
+	return lang.T("unit." + string(unit))
+}
+
+// formatTemperature форматирует температуру (°C) в единицах пользователя: "-3.5°C"
+func formatTemperature(lang i18n.Lang, u units.Preferences, celsius float64) string {
+	return fmt.Sprintf("%.1f%s", u.Temperature.FromCelsius(celsius), unitSymbol(lang, u.Temperature))
+}
+
+// formatSpeed форматирует скорость ветра (м/с) в единицах пользователя: "5.0 м/с"
+func formatSpeed(lang i18n.Lang, u units.Preferences, speed float64) string {
+	return fmt.Sprintf("%.1f %s", u.Speed.FromMetersPerSecond(speed), unitSymbol(lang, u.Speed))
+}
+
+// formatPressure форматирует давление (гПа) в единицах пользователя: "755 мм рт. ст."
+func formatPressure(lang i18n.Lang, u units.Preferences, pressure float64) string {
+	return fmt.Sprintf("%.0f %s", u.Pressure.FromHectopascals(pressure), unitSymbol(lang, u.Pressure))
+}
+
+// formatUnits перечисляет единицы измерения пользователя: "°C, м/с, гПа"
+func formatUnits(lang i18n.Lang, u units.Preferences) string {
+	return strings.Join(
+		[]string{unitSymbol(lang, u.Temperature), unitSymbol(lang, u.Speed), unitSymbol(lang, u.Pressure)},
+		", ",
+	)
+}
@@ -2,79 +2,417 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/qrave1/DeepCakeBot/internal/client/openweather"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/storage"
+	"github.com/qrave1/DeepCakeBot/internal/templates"
+	"github.com/qrave1/DeepCakeBot/internal/units"
 )
 
+const (
+	// weatherProvider - имя провайдера, под которым сохраняются наблюдения
+	weatherProvider = "openweather"
+
+	// comparisonTolerance - допустимое отклонение от "того же времени вчера"
+	comparisonTolerance = time.Hour
+
+	// notableWindChange - изменение скорости ветра (м/с), о котором стоит сообщить
+	notableWindChange = 3.0
+
+	// observationInterval - наблюдение для места сохраняется не чаще, чем раз в этот интервал:
+	// во время рассылки погоду в одном городе запрашивают для каждого пользователя
+	observationInterval = 10 * time.Minute
+
+	// forecastCacheTTL - сколько хранится полученный прогноз: OpenWeather обновляет его раз в несколько
+	// часов, а во время рассылки прогноз одного места нужен каждому пользователю
+	forecastCacheTTL = 10 * time.Minute
+)
+
+// WeatherReport содержит данные для сообщения с прогнозом погоды
+type WeatherReport struct {
+	LocationName string
+	Weather      *openweather.WeatherData
+	// Sections - разделы сообщения в порядке вывода
+	Sections []Section
+	// Alerts - сработавшие предупреждения
+	Alerts []Alert
+	// Comparison - сравнение со вчерашним днем, nil если сравнивать не с чем
+	Comparison *WeatherComparison
+	// Astro - восход, закат и фаза Луны, nil если раздел выключен
+	Astro *AstroData
+	// Air - качество воздуха, nil если раздел выключен или данных нет
+	Air *openweather.AirQuality
+	// Commute - прогноз на дорогу, nil если дорога не настроена или это не основной город
+	Commute *CommuteForecast
+	// Precipitation - периоды с осадками на день по прогнозу, пусто если осадков не ожидается
+	Precipitation []PrecipitationWindow
+}
+
+// WeatherComparison содержит изменения погоды относительно вчерашнего наблюдения
+type WeatherComparison struct {
+	TemperatureDelta float64
+	WindDelta        float64
+	RainStarted      bool
+	RainStopped      bool
+	SnowStarted      bool
+	SnowStopped      bool
+}
+
+// WindStronger сообщает, стал ли ветер заметно сильнее, чем вчера
+func (c *WeatherComparison) WindStronger() bool {
+	return c.WindDelta >= notableWindChange
+}
+
+// WindWeaker сообщает, стал ли ветер заметно слабее, чем вчера
+func (c *WeatherComparison) WindWeaker() bool {
+	return c.WindDelta <= -notableWindChange
+}
+
 // WeatherService предоставляет информацию о погоде и рекомендации
 type WeatherService struct {
-	client      *openweather.OpenWeatherClient
-	city        string
-	countryCode string
+	client          *openweather.OpenWeatherClient
+	observations    storage.ObservationRepository
+	retention       time.Duration
+	defaultLocation openweather.Location
+	messages        *templates.Renderer
+
+	// resolved - координаты мест, заданных только названием
+	resolved   map[string]openweather.Location
+	resolvedMu sync.Mutex
+
+	// recorded - время последнего сохраненного наблюдения по ключу локации
+	recorded *ttlCache[time.Time]
+
+	// forecasts - прогнозы по ключу места и языка описаний
+	forecasts *ttlCache[[]openweather.ForecastEntry]
 }
 
-// NewWeatherService создает новый сервис погоды
-func NewWeatherService(apiKey, city, countryCode string) *WeatherService {
+// NewWeatherService создает новый сервис погоды для города по умолчанию.
+// retention задает срок хранения наблюдений, 0 - хранить без ограничений.
+// messages - шаблоны сообщений, см. NewMessageTemplates.
+func NewWeatherService(
+	apiKey, city, countryCode string,
+	observations storage.ObservationRepository,
+	retention time.Duration,
+	messages *templates.Renderer,
+) *WeatherService {
 	return &WeatherService{
-		client:      openweather.NewOpenWeatherClient(apiKey),
-		city:        city,
-		countryCode: countryCode,
+		client:       openweather.NewOpenWeatherClient(apiKey),
+		observations: observations,
+		retention:    retention,
+		defaultLocation: openweather.Location{
+			Name:        city,
+			CountryCode: countryCode,
+		},
+		messages:  messages,
+		resolved:  make(map[string]openweather.Location),
+		recorded:  newTTLCache[time.Time](observationInterval),
+		forecasts: newTTLCache[[]openweather.ForecastEntry](forecastCacheTTL),
 	}
 }
 
-// GetWeather получает текущую погоду для заданного города
-func (s *WeatherService) GetWeather(ctx context.Context) (*openweather.WeatherData, error) {
-	return s.client.GetCurrentWeather(ctx, s.city, s.countryCode)
+// DefaultLocation возвращает место по умолчанию из конфигурации
+func (s *WeatherService) DefaultLocation() openweather.Location {
+	return s.defaultLocation
+}
+
+// LocationForUser возвращает сохраненное место пользователя или место по умолчанию
+func (s *WeatherService) LocationForUser(user *storage.User) openweather.Location {
+	if user.LocationName == "" {
+		return s.defaultLocation
+	}
+
+	return openweather.Location{
+		Name:      user.LocationName,
+		Latitude:  user.Latitude,
+		Longitude: user.Longitude,
+	}
 }
 
-// GetClothingRecommendation возвращает рекомендации по одежде на основе погоды
-func (s *WeatherService) GetClothingRecommendation(weather *openweather.WeatherData) string {
-	temp := weather.Temperature
-	var recommendation string
+// GetWeather получает текущую погоду для места с описанием на языке lang и сохраняет наблюдение
+func (s *WeatherService) GetWeather(ctx context.Context, location openweather.Location, lang i18n.Lang) (
+	*openweather.WeatherData,
+	error,
+) {
+	weather, err := s.client.GetCurrentWeather(ctx, location, string(lang))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordObservation(ctx, weather); err != nil {
+		log.Printf("Failed to record weather observation: %v", err)
+	}
+
+	return weather, nil
+}
+
+// GetAirQuality получает качество воздуха в месте, для которого получена погода
+func (s *WeatherService) GetAirQuality(ctx context.Context, weather *openweather.WeatherData) (
+	*openweather.AirQuality,
+	error,
+) {
+	return s.client.GetAirQuality(ctx, weather.Latitude, weather.Longitude)
+}
+
+// LocationName возвращает название места для сообщений: название от провайдера,
+// а если его нет - название из запроса
+func (s *WeatherService) LocationName(location openweather.Location, weather *openweather.WeatherData) string {
+	if weather != nil && weather.City != "" {
+		return weather.City
+	}
+
+	return location.Name
+}
+
+// resolveLocation дополняет место, заданное названием, координатами
+func (s *WeatherService) resolveLocation(ctx context.Context, location openweather.Location) (
+	openweather.Location,
+	error,
+) {
+	if location.HasCoordinates() {
+		return location, nil
+	}
+
+	key := strings.ToLower(location.Name + "," + location.CountryCode)
+
+	s.resolvedMu.Lock()
+	resolved, ok := s.resolved[key]
+	s.resolvedMu.Unlock()
+
+	if ok {
+		return resolved, nil
+	}
+
+	weather, err := s.GetWeather(ctx, location, i18n.Default)
+	if err != nil {
+		return openweather.Location{}, err
+	}
+
+	resolved = location
+	resolved.Latitude = weather.Latitude
+	resolved.Longitude = weather.Longitude
+
+	s.resolvedMu.Lock()
+	s.resolved[key] = resolved
+	s.resolvedMu.Unlock()
+
+	return resolved, nil
+}
+
+// observationKey возвращает ключ локации, под которым хранятся наблюдения
+func observationKey(latitude, longitude float64) string {
+	return fmt.Sprintf("%.2f,%.2f", latitude, longitude)
+}
 
+// MigrateObservationKeys переносит наблюдения, сохраненные до перехода на ключи из координат
+// под ключом из названия города, чтобы история не терялась для сравнений и сводок
+func (s *WeatherService) MigrateObservationKeys(ctx context.Context) error {
+	updated, err := s.observations.RekeyObservations(ctx, observationKey)
+	if err != nil {
+		return err
+	}
+
+	if updated > 0 {
+		log.Printf("Migrated %d weather observations to coordinate keys", updated)
+	}
+
+	return nil
+}
+
+// recordObservation сохраняет снимок погоды в историю наблюдений, если последнее наблюдение
+// для места сохранено больше observationInterval назад
+func (s *WeatherService) recordObservation(ctx context.Context, weather *openweather.WeatherData) error {
+	key := observationKey(weather.Latitude, weather.Longitude)
+	now := time.Now().UTC()
+
+	if last, ok := s.recorded.Get(key); ok && now.Sub(last) < observationInterval {
+		return nil
+	}
+
+	// Наблюдение могла сохранить другая реплика
+	latest, err := s.observations.GetLatestObservation(ctx, key)
+	switch {
+	case err == nil && now.Sub(latest.ObservedAt) < observationInterval:
+		s.recorded.Set(key, latest.ObservedAt)
+		return nil
+	case err != nil && !errors.Is(err, storage.ErrObservationNotFound):
+		return err
+	}
+
+	err = s.observations.SaveObservation(
+		ctx, &storage.WeatherObservation{
+			Location:    key,
+			Provider:    weatherProvider,
+			ObservedAt:  now,
+			Latitude:    weather.Latitude,
+			Longitude:   weather.Longitude,
+			Temperature: weather.Temperature,
+			FeelsLike:   weather.FeelsLike,
+			Description: weather.Description,
+			Humidity:    weather.Humidity,
+			WindSpeed:   weather.WindSpeed,
+			Pressure:    weather.Pressure,
+			Condition:   string(weather.Condition),
+			Night:       weather.Night,
+			Rain:        weather.IsRaining(),
+			Snow:        weather.IsSnowing(),
+			RainAmount:  weather.Rain,
+			SnowAmount:  weather.Snow,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s.recorded.Set(key, now)
+
+	return nil
+}
+
+// SampleObservations сохраняет наблюдения для города по умолчанию и мест, погоду в которых
+// смотрят пользователи. Так история наблюдений заполняется равномерно в течение дня,
+// а не только тогда, когда пользователи запрашивают погоду.
+func (s *WeatherService) SampleObservations(ctx context.Context) error {
+	coordinates, err := s.observations.GetLocationsInUse(ctx)
+	if err != nil {
+		return err
+	}
+
+	locations := []openweather.Location{s.defaultLocation}
+	for _, c := range coordinates {
+		locations = append(locations, openweather.Location{Latitude: c.Latitude, Longitude: c.Longitude})
+	}
+
+	// Места ближе друг к другу, чем точность ключа наблюдений, запрашиваются один раз
+	seen := make(map[string]bool, len(locations))
+
+	for _, location := range locations {
+		name := location.Name
+		if location.HasCoordinates() {
+			name = observationKey(location.Latitude, location.Longitude)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+		}
+
+		if _, err := s.GetWeather(ctx, location, i18n.Default); err != nil {
+			log.Printf("Failed to sample weather at %s: %v", name, err)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// CompareWithYesterday сравнивает погоду с наблюдением в это же время вчера.
+// Возвращает nil, если вчерашнего наблюдения нет.
+func (s *WeatherService) CompareWithYesterday(ctx context.Context, weather *openweather.WeatherData) (
+	*WeatherComparison,
+	error,
+) {
+	yesterday, err := s.observations.GetNearestObservation(
+		ctx,
+		observationKey(weather.Latitude, weather.Longitude),
+		time.Now().UTC().Add(-24*time.Hour),
+		comparisonTolerance,
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrObservationNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &WeatherComparison{
+		TemperatureDelta: weather.Temperature - yesterday.Temperature,
+		WindDelta:        weather.WindSpeed - yesterday.WindSpeed,
+		RainStarted:      weather.IsRaining() && !yesterday.Rain,
+		RainStopped:      !weather.IsRaining() && yesterday.Rain,
+		SnowStarted:      weather.IsSnowing() && !yesterday.Snow,
+		SnowStopped:      !weather.IsSnowing() && yesterday.Snow,
+	}, nil
+}
+
+// PruneObservations удаляет наблюдения старше срока хранения
+func (s *WeatherService) PruneObservations(ctx context.Context) error {
+	if s.retention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.observations.DeleteObservationsBefore(ctx, time.Now().UTC().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Pruned %d weather observations older than %s", deleted, s.retention)
+
+	return nil
+}
+
+// clothingForTemperature возвращает совет по одежде для температуры temp (°C)
+func clothingForTemperature(lang i18n.Lang, temp float64) string {
 	switch {
 	case temp < -15:
-		recommendation = "🧥 Очень холодно! Теплая зимняя одежда, шапка, шарф, перчатки обязательны."
+		return lang.T("clothing.very_cold")
 	case temp >= -15 && temp < -5:
-		recommendation = "❄️ Холодно. Зимняя куртка, теплые аксессуары (шапка, перчатки)."
+		return lang.T("clothing.cold")
 	case temp >= -5 && temp < 5:
-		recommendation = "🧥 Прохладно. Демисезонная куртка, можно добавить шарф."
+		return lang.T("clothing.chilly")
 	case temp >= 5 && temp < 15:
-		recommendation = "🧥 Прохладная погода. Легкая куртка или толстовка."
+		return lang.T("clothing.cool")
 	case temp >= 15 && temp < 25:
-		recommendation = "👕 Комфортная температура. Легкая одежда, можно без куртки."
+		return lang.T("clothing.comfortable")
 	default:
-		recommendation = "☀️ Жарко! Легкая летняя одежда, не забудьте солнцезащитные средства."
+		return lang.T("clothing.hot")
 	}
+}
 
-	if weather.Rain {
-		recommendation += "\n☔ Ожидается дождь - возьмите зонт или дождевик!"
-	}
-	if weather.Snow {
-		recommendation += "\n❄️ Ожидается снег - одевайтесь теплее и будьте осторожны на дорогах!"
+// FormatWeatherMessage форматирует сообщение с прогнозом погоды в единицах измерения u.
+// Сообщение собирается из разделов report.Sections по шаблону; пустые разделы пропускаются.
+func (s *WeatherService) FormatWeatherMessage(lang i18n.Lang, u units.Preferences, report *WeatherReport) (
+	string,
+	error,
+) {
+	data := newWeatherMessage(report)
+	locale, funcs := messageLocale(lang, u), messageFuncs(lang, u)
+
+	parts := make([]string, 0, len(report.Sections))
+	for _, section := range report.Sections {
+		part, err := s.messages.Render(string(section), locale, data, funcs)
+		if err != nil {
+			return "", err
+		}
+
+		if part != "" {
+			parts = append(parts, part)
+		}
 	}
 
-	return recommendation
+	return strings.Join(parts, "\n\n"), nil
 }
 
-// FormatWeatherMessage форматирует сообщение с прогнозом погоды
-func (s *WeatherService) FormatWeatherMessage(weather *openweather.WeatherData) string {
-	msg := fmt.Sprintf(
-		"🌤 Прогноз погоды для %s:\n\n"+
-			"🌡 Температура: %.1f°C (ощущается как %.1f°C)\n"+
-			"📝 Описание: %s\n"+
-			"💧 Влажность: %d%%\n"+
-			"💨 Скорость ветра: %.1f м/с\n\n"+
-			"%s",
-		s.city,
-		weather.Temperature,
-		weather.FeelsLike,
-		weather.Description,
-		weather.Humidity,
-		weather.WindSpeed,
-		s.GetClothingRecommendation(weather),
+// FormatSunMessage форматирует сообщение команды /sun по разделу "astro" шаблона
+func (s *WeatherService) FormatSunMessage(lang i18n.Lang, locationName string, data *AstroData) (string, error) {
+	section, err := s.messages.Render(
+		string(SectionAstro),
+		messageLocale(lang, units.Metric()),
+		weatherMessage{Astro: data},
+		messageFuncs(lang, units.Metric()),
 	)
+	if err != nil {
+		return "", err
+	}
 
-	return msg
+	return lang.T("sun.title", locationName) + "\n\n" + section, nil
 }
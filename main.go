@@ -8,7 +8,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/qrave1/DeepCakeBot/internal/broadcast"
 	"github.com/qrave1/DeepCakeBot/internal/config"
+	"github.com/qrave1/DeepCakeBot/internal/i18n"
+	"github.com/qrave1/DeepCakeBot/internal/leader"
+	"github.com/qrave1/DeepCakeBot/internal/queue"
 	"github.com/qrave1/DeepCakeBot/internal/storage"
 	"github.com/qrave1/DeepCakeBot/internal/usecase"
 
@@ -23,6 +27,15 @@ func main() {
 
 	log.Println("Starting DeepCake Bot...")
 
+	if err := i18n.Validate(); err != nil {
+		log.Fatalf("Invalid message catalog: %v", err)
+	}
+
+	messages, err := usecase.NewMessageTemplates(cfg.TemplatesDir)
+	if err != nil {
+		log.Fatalf("Invalid message templates: %v", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -49,21 +62,83 @@ func main() {
 
 	log.Println("Bot created successfully")
 
-	weatherService := usecase.NewWeatherService(cfg.OpenWeatherAPIKey, cfg.City, cfg.CountryCode)
+	weatherService := usecase.NewWeatherService(
+		cfg.OpenWeatherAPIKey,
+		cfg.City,
+		cfg.CountryCode,
+		db,
+		time.Duration(cfg.ObservationRetentionDays)*24*time.Hour,
+		messages,
+	)
+
+	if err := weatherService.MigrateObservationKeys(ctx); err != nil {
+		log.Printf("Failed to migrate weather observation keys: %v", err)
+	}
 
-	applicationBot := usecase.NewApplicationBot(bot, db, weatherService)
+	// Отправитель соблюдает лимиты Telegram для рассылок и ответов бота в этом процессе. Общий для всех
+	// реплик лимит рассылки соблюдает очередь задач, поэтому число реплик задавать не нужно.
+	sender := broadcast.New(float64(cfg.BroadcastRate))
+
+	applicationBot := usecase.NewApplicationBot(
+		bot,
+		db,
+		db,
+		db,
+		db,
+		weatherService,
+		sender,
+		cfg.Timezone,
+		cfg.WeatherScheduleHour,
+	)
 
 	applicationBot.RegisterHandlers()
 	log.Println("Bot handlers registered")
 
-	scheduler, err := usecase.NewScheduler(db, applicationBot, cfg.Timezone, cfg.WeatherScheduleHour)
+	// Очередь задач выполняет утреннюю рассылку, сводки и другие отложенные задачи на всех репликах
+	jobQueue := queue.New(db, cfg.QueueWorkers)
+
+	scheduler, err := usecase.NewScheduler(
+		db,
+		db,
+		db,
+		jobQueue,
+		applicationBot,
+		weatherService,
+		cfg.Timezone,
+		cfg.WeatherScheduleHour,
+		cfg.DigestScheduleHour,
+		time.Duration(cfg.DeliveryRetryMinutes)*time.Minute,
+		time.Duration(cfg.CatchUpMinutes)*time.Minute,
+		float64(cfg.BroadcastRate),
+	)
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)
 	}
 	scheduler.Start(ctx)
 
-	go bot.Start()
-	log.Println("Bot started and listening for messages...")
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		jobQueue.Run(ctx)
+	}()
+
+	// Обновления от Telegram получает только ведущая реплика: long polling одного токена
+	// с нескольких реплик не работает. Задачи очереди делят все реплики.
+	electorDone := make(chan struct{})
+	go func() {
+		defer close(electorDone)
+
+		leader.New(db).Run(
+			ctx, func(ctx context.Context) {
+				go bot.Start()
+				log.Println("Bot started and listening for messages...")
+
+				<-ctx.Done()
+				bot.Stop()
+				log.Println("Bot stopped listening for messages")
+			},
+		)
+	}()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
@@ -71,11 +146,9 @@ func main() {
 	<-sigChan
 	log.Println("Shutdown signal received, gracefully shutting down...")
 
-	scheduler.Stop()
-
-	bot.Stop()
-
 	cancel()
+	<-electorDone
+	<-queueDone
 
 	time.Sleep(2 * time.Second)
 